@@ -0,0 +1,173 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"github.com/soniakeys/bits"
+	"github.com/soniakeys/graph"
+)
+
+// SplitSupport holds bootstrap support for splits.
+//
+// Keys are the strings of Split.Leaves.  Values are the fraction of
+// replicates containing the split.
+type SplitSupport map[string]float64
+
+// Of returns the support for split s, 0 if s was in no replicate.
+func (ss SplitSupport) Of(s Split) float64 {
+	return ss[s.Leaves.String()]
+}
+
+// Bootstrap builds trees for nRep bootstrap replicates and returns the
+// support for each split found.
+//
+// Function replicate returns the distance matrix for a replicate, typically
+// constructed from resampled data as with ResampleColumns.  It is passed a
+// random number generator for the replicate.  Function build constructs a
+// tree from the matrix and returns its splits.  Use NeighborJoinSplits or
+// UltrametricSplits, or a function giving splits in the same form as the
+// splits of the reference tree.  Build may be destructive on the matrix.
+//
+// Replicates are run in parallel, with up to GOMAXPROCS goroutines.
+// Replicate and build must be safe to call concurrently.  Each replicate
+// uses a random number generator seeded from rnd, so results are
+// reproducible for a given rnd.  If rnd is nil, seeds are taken from the
+// math/rand default source.
+func Bootstrap(nRep int, replicate func(*rand.Rand) DistanceMatrix, build func(DistanceMatrix) []Split, rnd *rand.Rand) SplitSupport {
+	seeds := make([]int64, nRep)
+	for i := range seeds {
+		if rnd != nil {
+			seeds[i] = rnd.Int63()
+		} else {
+			seeds[i] = rand.Int63()
+		}
+	}
+	count := map[string]int{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	next := make(chan int64)
+	nw := runtime.GOMAXPROCS(0)
+	if nw > nRep {
+		nw = nRep
+	}
+	for w := 0; w < nw; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := map[string]int{}
+			for seed := range next {
+				dm := replicate(rand.New(rand.NewSource(seed)))
+				for _, s := range build(dm) {
+					c[s.Leaves.String()]++
+				}
+			}
+			mu.Lock()
+			for k, n := range c {
+				count[k] += n
+			}
+			mu.Unlock()
+		}()
+	}
+	for _, s := range seeds {
+		next <- s
+	}
+	close(next)
+	wg.Wait()
+	ss := make(SplitSupport, len(count))
+	for k, n := range count {
+		ss[k] = float64(n) / float64(nRep)
+	}
+	return ss
+}
+
+// ResampleColumns returns a bootstrap sample of the coordinates of points.
+//
+// Coordinates, or columns, are drawn with replacement using rnd, and the
+// same columns are drawn for all points.  The result can be passed to
+// NewEuclideanDist or NewPearsonDist to construct a replicate matrix.  The
+// number of columns is taken from the first point.
+func ResampleColumns(points []Point, rnd *rand.Rand) []Point {
+	if len(points) == 0 {
+		return nil
+	}
+	nc := len(points[0])
+	cols := make([]int, nc)
+	for i := range cols {
+		cols[i] = rnd.Intn(nc)
+	}
+	r := make([]Point, len(points))
+	for i, p := range points {
+		rp := make(Point, nc)
+		for j, c := range cols {
+			rp[j] = p[c]
+		}
+		r[i] = rp
+	}
+	return r
+}
+
+// NeighborJoinSplits constructs a tree with NeighborJoinD and returns its
+// bipartitions.
+//
+// It is destructive on dm.  It is suitable as the build function of
+// Bootstrap for a reference tree constructed with NeighborJoin.
+func NeighborJoinSplits(dm DistanceMatrix) []Split {
+	n := len(dm)
+	u, wt := dm.NeighborJoinD()
+	return Bipartitions(u, wt, n)
+}
+
+// UltrametricSplits returns a function that constructs a tree with
+// UltrametricD and returns its clusters.
+//
+// The function is destructive on its argument.  It is suitable as the build
+// function of Bootstrap for a reference tree constructed with Ultrametric
+// with the same cluster distance function cdf.
+func UltrametricSplits(cdf int) func(DistanceMatrix) []Split {
+	return func(dm DistanceMatrix) []Split {
+		n := len(dm)
+		pl, ul := dm.UltrametricD(cdf)
+		return Clusters(pl, ul, n)
+	}
+}
+
+// EdgeSupport returns the support of each edge of an unrooted tree.
+//
+// The tree is given as an undirected graph with edge labels, as returned
+// by NeighborJoin.  Leaves must be nodes 0:nLeaves.  The result is indexed
+// by edge label.  Pendant edges, which are in all trees, are given NaN.
+func EdgeSupport(u graph.LabeledUndirected, nLeaves int, ss SplitSupport) []float64 {
+	var sup []float64
+	edgeSplits(u.LabeledAdjacencyList, nLeaves, func(l graph.LI, b bits.Bits) {
+		for int(l) >= len(sup) {
+			sup = append(sup, math.NaN())
+		}
+		if s := (Split{Leaves: b}); !s.Trivial() {
+			sup[l] = ss.Of(s)
+		}
+	})
+	return sup
+}
+
+// NodeSupport returns the support of the cluster of each node of a rooted
+// tree.
+//
+// The tree is given as a parent list as returned by Ultrametric.  Leaves
+// must be nodes 0:nLeaves.  The result is indexed by node.  Leaves and the
+// root, which are in all trees, are given NaN.
+func NodeSupport(pl graph.FromList, nLeaves int, ss SplitSupport) []float64 {
+	sup := make([]float64, len(pl.Paths))
+	for n, b := range nodeLeaves(pl, nLeaves) {
+		if pl.Paths[n].From < 0 || b.OnesCount() <= 1 {
+			sup[n] = math.NaN()
+		} else {
+			sup[n] = ss.Of(Split{Leaves: b})
+		}
+	}
+	return sup
+}
@@ -0,0 +1,72 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+	"github.com/soniakeys/graph"
+)
+
+// bootPoints returns points in two well separated groups with noise.
+func bootPoints(r *rand.Rand, n, dim int) []cluster.Point {
+	pts := make([]cluster.Point, n)
+	for i := range pts {
+		p := make(cluster.Point, dim)
+		for j := range p {
+			p[j] = r.NormFloat64()
+			if i < n/2 {
+				p[j] += 5
+			}
+		}
+		pts[i] = p
+	}
+	return pts
+}
+
+func ExampleBootstrap() {
+	pts := bootPoints(rand.New(rand.NewSource(1)), 6, 30)
+	u, _ := cluster.NewEuclideanDist(pts).NeighborJoin()
+	ss := cluster.Bootstrap(100, func(r *rand.Rand) cluster.DistanceMatrix {
+		return cluster.NewEuclideanDist(cluster.ResampleColumns(pts, r))
+	}, cluster.NeighborJoinSplits, rand.New(rand.NewSource(2)))
+	for l, s := range cluster.EdgeSupport(u, len(pts), ss) {
+		if !math.IsNaN(s) {
+			fmt.Println("edge", l, "support", s)
+		}
+	}
+	// Output:
+	// edge 0 support 1
+	// edge 1 support 0.68
+	// edge 6 support 0.57
+}
+
+func TestBootstrap(t *testing.T) {
+	pts := bootPoints(rand.New(rand.NewSource(3)), 8, 50)
+	rep := func(r *rand.Rand) cluster.DistanceMatrix {
+		return cluster.NewEuclideanDist(cluster.ResampleColumns(pts, r))
+	}
+	build := cluster.UltrametricSplits(cluster.DAVG)
+	ss1 := cluster.Bootstrap(50, rep, build, rand.New(rand.NewSource(4)))
+	ss2 := cluster.Bootstrap(50, rep, build, rand.New(rand.NewSource(4)))
+	if !reflect.DeepEqual(ss1, ss2) {
+		t.Fatal("results differ for the same seed")
+	}
+	pl, ul := cluster.NewEuclideanDist(pts).Ultrametric(cluster.DAVG)
+	sup := cluster.NodeSupport(pl, len(pts), ss1)
+	root := len(pl.Paths) - 1
+	if !math.IsNaN(sup[0]) || !math.IsNaN(sup[root]) {
+		t.Fatal("leaf or root support not NaN:", sup[0], sup[root])
+	}
+	// the two groups are clusters of every replicate
+	for _, c := range cluster.NewDendrogram(pl, ul).Children(graph.NI(root)) {
+		if sup[c] != 1 {
+			t.Fatal("group support", sup[c])
+		}
+	}
+}
@@ -6,6 +6,22 @@ package cluster
 // represented by the two indexes.
 type SimilarityMatrix [][]float64
 
+// Clone allocates and copies a SimilarityMatrix.
+func (sim SimilarityMatrix) Clone() SimilarityMatrix {
+	sc := make(SimilarityMatrix, len(sim))
+	for i, si := range sim {
+		sc[i] = append([]float64{}, si...)
+	}
+	return sc
+}
+
+// Sub allocates and returns the submatrix of sim with rows and columns x.
+//
+// See DistanceMatrix.Sub.
+func (sim SimilarityMatrix) Sub(x []int) SimilarityMatrix {
+	return SimilarityMatrix(subMatrix(sim, x))
+}
+
 // CAST implements "Cluster Affinity Search Technique"
 //
 // Argument v is a similarity threshold.  If a graph is constructed with
@@ -135,3 +151,21 @@ func NewPearsonDist(exp []Point) DistanceMatrix {
 	}
 	return dist
 }
+
+// Similarity constructs a similarity matrix from a distance matrix.
+//
+// Elements are max - d[i][j], so the diagonal has similarity max and points
+// at distance max have similarity 0.  The CAST threshold v then corresponds
+// to a distance threshold max - v.  For edit distances computed with a
+// threshold k, max = k+1 gives all pairs beyond the threshold similarity 0.
+func (d DistanceMatrix) Similarity(max float64) SimilarityMatrix {
+	sim := make(SimilarityMatrix, len(d))
+	for i, di := range d {
+		si := make([]float64, len(di))
+		for j, dij := range di {
+			si[j] = max - dij
+		}
+		sim[i] = si
+	}
+	return sim
+}
@@ -0,0 +1,219 @@
+// Public domain.
+
+package cluster
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/soniakeys/bits"
+	"github.com/soniakeys/graph"
+)
+
+// ConsensusStrict, ConsensusMajority, ConsensusExtended constants for the
+// rule argument of consensus functions.
+const (
+	ConsensusStrict   = iota // splits in all trees
+	ConsensusMajority        // splits in more than half of the trees
+	ConsensusExtended        // majority, then compatible splits by frequency
+)
+
+// ConsensusSplits returns the splits of a consensus of trees.
+//
+// Each tree is given by its splits, as returned by Bipartitions or
+// Clusters.  All trees must be on the same leaves and of the same form.
+// If rooted is true, splits are taken as clusters of rooted trees, as
+// returned by Clusters.  Otherwise they are taken as bipartitions of
+// unrooted trees.  A bipartition may be given by either side; the result
+// gives each by the side not containing leaf 0, as with Bipartitions.
+//
+// With rule ConsensusStrict, the result has the splits found in all trees.
+// With ConsensusMajority it has the splits found in more than half of the
+// trees.  With ConsensusExtended it has the majority splits and then, in
+// order of decreasing frequency, each further split compatible with those
+// already accepted.  Splits of equal frequency are taken in order of first
+// appearance in trees.
+//
+// Splits common to all trees on the leaves, those of single leaves, all
+// leaves, or for bipartitions all leaves but one, are omitted.  Weights
+// of the result are the fraction of trees containing each split.  The
+// result is ordered by decreasing frequency.
+func ConsensusSplits(trees [][]Split, rule int, rooted bool) []Split {
+	trivial := Split.Trivial
+	if rooted {
+		trivial = Split.TrivialRooted
+	}
+	count := map[string]int{}
+	var all []Split // distinct splits in order of first appearance
+	for _, t := range trees {
+		seen := map[string]bool{}
+		if !rooted {
+			t = Unroot(t)
+		}
+		for _, s := range t {
+			if trivial(s) {
+				continue
+			}
+			k := s.Leaves.String()
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			if count[k] == 0 {
+				all = append(all, Split{Leaves: s.Leaves})
+			}
+			count[k]++
+		}
+	}
+	for i, s := range all {
+		all[i].Weight = float64(count[s.Leaves.String()]) / float64(len(trees))
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Weight > all[j].Weight
+	})
+	var r []Split
+	for _, s := range all {
+		switch {
+		case s.Weight == 1:
+		case s.Weight > .5 && rule != ConsensusStrict:
+		case rule == ConsensusExtended && compatible(s, r):
+		default:
+			continue
+		}
+		r = append(r, s)
+	}
+	return r
+}
+
+// compatible tests if split s is compatible with all of splits r, that is
+// that each is a subset of the other or they are disjoint.
+func compatible(s Split, r []Split) bool {
+	var t bits.Bits
+	for _, x := range r {
+		t.And(s.Leaves, x.Leaves)
+		if !(t.AllZeros() || t.Equal(s.Leaves) || t.Equal(x.Leaves)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ConsensusTree is a rooted tree, possibly with multifurcations, built from
+// compatible splits.
+//
+// Leaves are nodes 0:NLeaves.  Internal nodes follow in order of increasing
+// number of leaves, with the root last.  As with Ultrametric results,
+// PathEnd.Len is the number of leaves under each node.  Freq holds the
+// Weight of the split for each internal node, which for a consensus is the
+// fraction of trees containing the split.  Freq is 1 for leaves and the
+// root.
+//
+// For splits of unrooted trees, the root is the node adjacent to leaf 0 and
+// the tree should be read as unrooted.
+type ConsensusTree struct {
+	graph.FromList
+	Freq    []float64
+	NLeaves int
+}
+
+// Consensus constructs a consensus tree.
+//
+// See ConsensusSplits for the arguments.
+func Consensus(trees [][]Split, rule int, rooted bool) *ConsensusTree {
+	n := 0
+	for _, t := range trees {
+		for _, s := range t {
+			n = s.Leaves.Num
+		}
+	}
+	return NewConsensusTree(ConsensusSplits(trees, rule, rooted), n)
+}
+
+// NewConsensusTree constructs a tree from compatible splits on nLeaves
+// leaves.
+//
+// Splits should be as returned by ConsensusSplits and must not include
+// splits of single leaves or of all leaves.  The result is not meaningful
+// if splits are incompatible.
+func NewConsensusTree(splits []Split, nLeaves int) *ConsensusTree {
+	s := append([]Split{}, splits...)
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Leaves.OnesCount() < s[j].Leaves.OnesCount()
+	})
+	nn := nLeaves + len(s) + 1
+	root := graph.NI(nn - 1)
+	p := make([]graph.PathEnd, nn)
+	freq := make([]float64, nn)
+	for n := range p {
+		p[n] = graph.PathEnd{From: root, Len: 1}
+		freq[n] = 1
+	}
+	p[root] = graph.PathEnd{From: -1, Len: nLeaves}
+	var t bits.Bits
+	for i, si := range s {
+		n := nLeaves + i
+		p[n].Len = si.Leaves.OnesCount()
+		freq[n] = si.Weight
+		// the smallest larger split containing si is the parent.
+		for j := i + 1; j < len(s); j++ {
+			t.AndNot(si.Leaves, s[j].Leaves)
+			if t.AllZeros() {
+				p[n].From = graph.NI(nLeaves + j)
+				break
+			}
+		}
+	}
+	for l := 0; l < nLeaves; l++ {
+		for i, si := range s {
+			if si.Leaves.Bit(l) == 1 {
+				p[l].From = graph.NI(nLeaves + i)
+				break
+			}
+		}
+	}
+	return &ConsensusTree{graph.FromList{Paths: p}, freq, nLeaves}
+}
+
+// WriteNewick writes the consensus tree in Newick format.
+//
+// Leaves are labeled as for WriteNewick.  Internal nodes other than the root
+// are labeled with their frequencies.  Branch lengths are not written.
+// Children are written in order of node number.
+func (ct *ConsensusTree) WriteNewick(w io.Writer, names []string) error {
+	p := ct.Paths
+	ch := make([][]graph.NI, len(p))
+	root := graph.NI(-1)
+	for n, pn := range p {
+		if pn.From >= 0 {
+			ch[pn.From] = append(ch[pn.From], graph.NI(n))
+		} else {
+			root = graph.NI(n)
+		}
+	}
+	var b bytes.Buffer
+	var f func(graph.NI)
+	f = func(n graph.NI) {
+		if len(ch[n]) == 0 {
+			b.WriteString(newickLeaf(n, names))
+			return
+		}
+		b.WriteByte('(')
+		for i, c := range ch[n] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			f(c)
+		}
+		b.WriteByte(')')
+		if n != root {
+			b.WriteString(newickFloat(ct.Freq[n]))
+		}
+	}
+	if root >= 0 {
+		f(root)
+	}
+	b.WriteString(";\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
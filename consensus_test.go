@@ -0,0 +1,130 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleConsensus() {
+	var names []string
+	var trees [][]cluster.Split
+	for _, nw := range []string{
+		"((a,b),c,(d,e));",
+		"((a,b),d,(c,e));",
+		"((a,b),e,(c,d));",
+		"((a,c),b,(d,e));",
+	} {
+		t, _ := cluster.ReadNewickTree(strings.NewReader(nw))
+		if names == nil {
+			names = t.Names
+		}
+		s, _ := cluster.RelabelSplits(t.Bipartitions(), t.Names, names)
+		trees = append(trees, cluster.Unroot(s))
+	}
+	for _, rule := range []int{
+		cluster.ConsensusStrict,
+		cluster.ConsensusMajority,
+		cluster.ConsensusExtended,
+	} {
+		cluster.Consensus(trees, rule, false).WriteNewick(os.Stdout, names)
+	}
+	// Output:
+	// (a,b,c,d,e);
+	// (a,b,(c,d,e)0.75);
+	// (a,b,(c,(d,e)0.5)0.75);
+}
+
+func TestConsensus(t *testing.T) {
+	for _, n := range []int{3, 5, 20} {
+		d := cluster.RandomAdditiveMatrix(n)
+		u, wt := d.AdditiveTree()
+		b := cluster.Bipartitions(u, wt, n)
+		trees := [][]cluster.Split{b, b, b}
+		var nt []cluster.Split // non-trivial splits of b
+		for _, s := range b {
+			if !s.Trivial() {
+				nt = append(nt, s)
+			}
+		}
+		if rf := cluster.RobinsonFoulds(nt,
+			cluster.ConsensusSplits(trees, cluster.ConsensusStrict, false)); rf != 0 {
+			t.Fatal("n =", n, "strict consensus RF", rf)
+		}
+		// splits of the consensus tree, rooted next to leaf 0
+		ct := cluster.Consensus(trees, cluster.ConsensusMajority, false)
+		c := cluster.Unroot(cluster.Clusters(ct.FromList,
+			make([]cluster.Ultrametric, len(ct.Paths)), n))
+		if rf := cluster.RobinsonFoulds(b, c); rf != 0 {
+			t.Fatal("n =", n, "consensus tree RF", rf)
+		}
+		// rooted
+		pl, ul := d.Ultrametric(cluster.DAVG)
+		cl := cluster.Clusters(pl, ul, n)
+		ct = cluster.Consensus([][]cluster.Split{cl, cl}, cluster.ConsensusStrict, true)
+		if rf := cluster.RobinsonFoulds(cl, cluster.Clusters(ct.FromList,
+			make([]cluster.Ultrametric, len(ct.Paths)), n)); rf != 0 {
+			t.Fatal("n =", n, "rooted consensus RF", rf)
+		}
+	}
+}
+
+func TestConsensusForm(t *testing.T) {
+	// rooted (((b,c),d),a) and (((b,d),c),a), filtered to non-trivial
+	// clusters, none of which contain leaf 0
+	nonTrivial := func(d cluster.DistanceMatrix) (s []cluster.Split) {
+		pl, ul := d.Ultrametric(cluster.DAVG)
+		for _, sp := range cluster.Clusters(pl, ul, len(d)) {
+			if !sp.TrivialRooted() {
+				s = append(s, sp)
+			}
+		}
+		return
+	}
+	trees := [][]cluster.Split{
+		nonTrivial(cluster.DistanceMatrix{
+			{0, 8, 8, 8},
+			{8, 0, 2, 4},
+			{8, 2, 0, 4},
+			{8, 4, 4, 0},
+		}),
+		nonTrivial(cluster.DistanceMatrix{
+			{0, 8, 8, 8},
+			{8, 0, 4, 2},
+			{8, 4, 0, 4},
+			{8, 2, 4, 0},
+		}),
+	}
+	s := cluster.ConsensusSplits(trees, cluster.ConsensusStrict, true)
+	if len(s) != 1 || fmt.Sprint(s[0].Leaves.Slice()) != "[1 2 3]" {
+		t.Fatal("rooted", s)
+	}
+	// unrooted, relabeled without Unroot so that splits may contain leaf 0
+	var names []string
+	trees = nil
+	for _, nw := range []string{
+		"((a,b),c,(d,e));",
+		"((d,e),c,(b,a));",
+	} {
+		tr, _ := cluster.ReadNewickTree(strings.NewReader(nw))
+		if names == nil {
+			names = tr.Names
+		}
+		s, _ := cluster.RelabelSplits(tr.Bipartitions(), tr.Names, names)
+		trees = append(trees, s)
+	}
+	s = cluster.ConsensusSplits(trees, cluster.ConsensusStrict, false)
+	if len(s) != 2 {
+		t.Fatal("unrooted", s)
+	}
+	for _, sp := range s {
+		if sp.Leaves.Bit(0) == 1 {
+			t.Fatal("unrooted split contains leaf 0", sp.Leaves.Slice())
+		}
+	}
+}
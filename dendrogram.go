@@ -0,0 +1,273 @@
+// Public domain.
+
+package cluster
+
+import (
+	"strconv"
+
+	"github.com/soniakeys/graph"
+)
+
+// Dendrogram wraps the rooted tree returned by DistanceMatrix.Ultrametric
+// with indexes for efficient queries.
+//
+// The parent list and Ultrametric labels are kept as given.  Dendrogram
+// adds an ordered child list for each node, the depth-first order of leaves
+// implied by the child lists, and ancestor tables for lowest common ancestor
+// queries.
+//
+// Leaves are nodes with no children.  As with Ultrametric results they are
+// expected to be nodes 0:NLeaves.
+type Dendrogram struct {
+	graph.FromList               // parent list
+	Labels         []Ultrametric // labels for the parent list
+	NLeaves        int           // number of leaves
+	Root           graph.NI      // root node
+	Names          []string      // leaf names, optional
+
+	ch    [][]graph.NI // ordered child lists
+	order []graph.NI   // depth-first leaf order
+	first []int        // leaves under n are order[first[n]:last[n]]
+	last  []int
+	depth []int        // number of edges from root
+	up    [][]graph.NI // up[k][n] is the 2^k-th ancestor of n, -1 above root
+}
+
+// NewDendrogram constructs a Dendrogram from a parent list and labels as
+// returned by DistanceMatrix.Ultrametric.
+//
+// Children of each node are initially ordered by node number.
+func NewDendrogram(pl graph.FromList, ul []Ultrametric) *Dendrogram {
+	ch := make([][]graph.NI, len(pl.Paths))
+	for n, p := range pl.Paths {
+		if p.From >= 0 {
+			ch[p.From] = append(ch[p.From], graph.NI(n))
+		}
+	}
+	return newDendrogram(pl, ul, ch)
+}
+
+// Dendrogram constructs a Dendrogram from the result of
+// DistanceMatrix.Ultrametric.
+//
+// Argument cdf is the cluster distance function, DAVG or DMIN.
+// The receiver is not modified.
+func (dm DistanceMatrix) Dendrogram(cdf int) *Dendrogram {
+	return NewDendrogram(dm.Ultrametric(cdf))
+}
+
+// newDendrogram builds the indexes for the given child lists.
+func newDendrogram(pl graph.FromList, ul []Ultrametric, ch [][]graph.NI) *Dendrogram {
+	nn := len(pl.Paths)
+	dg := &Dendrogram{
+		FromList: pl,
+		Labels:   ul,
+		Root:     -1,
+		ch:       ch,
+		first:    make([]int, nn),
+		last:     make([]int, nn),
+		depth:    make([]int, nn),
+	}
+	for n, p := range pl.Paths {
+		if p.From < 0 {
+			dg.Root = graph.NI(n)
+		}
+		if len(ch[n]) == 0 {
+			dg.NLeaves++
+		}
+	}
+	if dg.Root < 0 {
+		return dg
+	}
+	dg.order = make([]graph.NI, 0, dg.NLeaves)
+	var df func(graph.NI, int)
+	df = func(n graph.NI, d int) {
+		dg.depth[n] = d
+		dg.first[n] = len(dg.order)
+		if len(ch[n]) == 0 {
+			dg.order = append(dg.order, n)
+		}
+		for _, c := range ch[n] {
+			df(c, d+1)
+		}
+		dg.last[n] = len(dg.order)
+	}
+	df(dg.Root, 0)
+	// ancestor tables for LCA by binary lifting
+	up0 := make([]graph.NI, nn)
+	for n, p := range pl.Paths {
+		up0[n] = p.From
+	}
+	dg.up = [][]graph.NI{up0}
+	for k := 1; 1<<uint(k) < nn; k++ {
+		prev := dg.up[k-1]
+		upk := make([]graph.NI, nn)
+		for n, a := range prev {
+			if a < 0 {
+				upk[n] = -1
+			} else {
+				upk[n] = prev[a]
+			}
+		}
+		dg.up = append(dg.up, upk)
+	}
+	return dg
+}
+
+// Children returns the ordered child list of node n.
+//
+// The returned slice is shared with the Dendrogram and should not be
+// modified.
+func (dg *Dendrogram) Children(n graph.NI) []graph.NI {
+	return dg.ch[n]
+}
+
+// LeafOrder returns all leaves in depth-first order.
+//
+// The order is that of a drawing of the dendrogram where children of each
+// node are drawn in the order of the child lists.  The returned slice is
+// shared with the Dendrogram and should not be modified.
+func (dg *Dendrogram) LeafOrder() []graph.NI {
+	return dg.order
+}
+
+// Leaves returns the leaves under node n, in depth-first order.
+//
+// The result is a subslice of LeafOrder and should not be modified.
+func (dg *Dendrogram) Leaves(n graph.NI) []graph.NI {
+	return dg.order[dg.first[n]:dg.last[n]]
+}
+
+// Contains returns true if leaf is a leaf under node n, that is, if leaf
+// is a member of the cluster represented by n.
+//
+// Time complexity is O(1).
+func (dg *Dendrogram) Contains(n, leaf graph.NI) bool {
+	return dg.Ancestor(n, leaf)
+}
+
+// Ancestor returns true if node a is node n or an ancestor of node n.
+//
+// Time complexity is O(1).
+func (dg *Dendrogram) Ancestor(a, n graph.NI) bool {
+	if a == n {
+		return true
+	}
+	// n is a descendant of a when n is deeper and n's leaf range nests
+	// within a's.  (Leaf ranges are never empty.)
+	return dg.depth[n] > dg.depth[a] &&
+		dg.first[n] >= dg.first[a] && dg.last[n] <= dg.last[a]
+}
+
+// Depth returns the number of edges on the path from the root to node n.
+func (dg *Dendrogram) Depth(n graph.NI) int {
+	return dg.depth[n]
+}
+
+// LCA returns the lowest common ancestor of nodes a and b.
+//
+// Time complexity is O(log n) in the number of nodes.
+func (dg *Dendrogram) LCA(a, b graph.NI) graph.NI {
+	if dg.depth[a] < dg.depth[b] {
+		a, b = b, a
+	}
+	// lift a to the depth of b
+	for k, δ := 0, dg.depth[a]-dg.depth[b]; δ > 0; k, δ = k+1, δ>>1 {
+		if δ&1 == 1 {
+			a = dg.up[k][a]
+		}
+	}
+	if a == b {
+		return a
+	}
+	for k := len(dg.up) - 1; k >= 0; k-- {
+		if ua, ub := dg.up[k][a], dg.up[k][b]; ua != ub {
+			a, b = ua, ub
+		}
+	}
+	return dg.up[0][a]
+}
+
+// MergeHeight returns the height at which the clusters containing nodes a
+// and b are merged, the Age of their lowest common ancestor.
+func (dg *Dendrogram) MergeHeight(a, b graph.NI) float64 {
+	return dg.Labels[dg.LCA(a, b)].Age
+}
+
+// Cut partitions the leaves into k clusters.
+//
+// Each cluster is the set of leaves under a subtree.  Subtrees are found by
+// removing the k-1 internal nodes of greatest Age.  If k is greater than
+// NLeaves, NLeaves clusters are returned.  Clusters are returned in leaf
+// order.  Each cluster is a newly allocated slice that the caller may
+// modify.
+func (dg *Dendrogram) Cut(k int) (clusters [][]graph.NI) {
+	if k > dg.NLeaves {
+		k = dg.NLeaves
+	}
+	if k < 1 || dg.Root < 0 {
+		return nil
+	}
+	// cut set, expanded by replacing the oldest node with its children.
+	cut := []graph.NI{dg.Root}
+	for len(cut) < k {
+		x := -1
+		for i, n := range cut {
+			if len(dg.ch[n]) > 0 &&
+				(x < 0 || dg.Labels[n].Age > dg.Labels[cut[x]].Age) {
+				x = i
+			}
+		}
+		n := cut[x]
+		cut = append(cut[:x], append(append([]graph.NI{}, dg.ch[n]...),
+			cut[x+1:]...)...)
+	}
+	for _, n := range cut {
+		clusters = append(clusters, append([]graph.NI{}, dg.Leaves(n)...))
+	}
+	return
+}
+
+// CutHeight partitions the leaves into clusters that are merged below
+// height h.
+//
+// Each cluster is the set of leaves under a maximal subtree with root Age
+// at most h.  Clusters are returned in leaf order.  Each cluster is a newly
+// allocated slice that the caller may modify.
+func (dg *Dendrogram) CutHeight(h float64) (clusters [][]graph.NI) {
+	if dg.Root < 0 {
+		return nil
+	}
+	var f func(graph.NI)
+	f = func(n graph.NI) {
+		if dg.Labels[n].Age <= h || len(dg.ch[n]) == 0 {
+			clusters = append(clusters, append([]graph.NI{}, dg.Leaves(n)...))
+			return
+		}
+		for _, c := range dg.ch[n] {
+			f(c)
+		}
+	}
+	f(dg.Root)
+	return
+}
+
+// ClusterNames returns the leaf names of clusters such as those returned by
+// Cut or CutHeight.
+//
+// Leaves without a name in dg.Names are represented by their node numbers.
+func (dg *Dendrogram) ClusterNames(clusters [][]graph.NI) [][]string {
+	cn := make([][]string, len(clusters))
+	for i, c := range clusters {
+		ci := make([]string, len(c))
+		for j, l := range c {
+			if int(l) < len(dg.Names) {
+				ci[j] = dg.Names[l]
+			} else {
+				ci[j] = strconv.Itoa(int(l))
+			}
+		}
+		cn[i] = ci
+	}
+	return cn
+}
@@ -0,0 +1,129 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+	"github.com/soniakeys/graph"
+)
+
+func ExampleDendrogram() {
+	d := cluster.DistanceMatrix{
+		{0, 20, 17, 11},
+		{20, 0, 20, 13},
+		{17, 20, 0, 10},
+		{11, 13, 10, 0},
+	}
+	dg := d.Dendrogram(cluster.DAVG)
+	fmt.Println("root:", dg.Root)
+	fmt.Println("children of 5:", dg.Children(5))
+	fmt.Println("leaves under 5:", dg.Leaves(5))
+	fmt.Println("leaf order:", dg.LeafOrder())
+	fmt.Println("LCA(0, 2):", dg.LCA(0, 2))
+	fmt.Println("merge height(2, 3):", dg.MergeHeight(2, 3))
+	fmt.Println("5 contains 1:", dg.Contains(5, 1))
+	// Output:
+	// root: 6
+	// children of 5: [0 4]
+	// leaves under 5: [0 2 3]
+	// leaf order: [1 0 2 3]
+	// LCA(0, 2): 5
+	// merge height(2, 3): 5
+	// 5 contains 1: false
+}
+
+func ExampleDendrogram_Cut() {
+	exp := []cluster.Point{
+		{10, 8, 10},
+		{10, 0, 9},
+		{4, 8.5, 3},
+		{9.5, .5, 8.5},
+		{4.5, 8.5, 2.5},
+		{10.5, 9, 12},
+		{5, 8.5, 11},
+		{3.7, 8.7, 2},
+		{9.7, 2, 9},
+		{10.2, 1, 9.2},
+	}
+	dg := cluster.NewEuclideanDist(exp).Dendrogram(cluster.DAVG)
+	for _, c := range dg.Cut(4) {
+		for _, x := range c {
+			fmt.Printf("%d: %g\n", x, exp[x])
+		}
+		fmt.Println()
+	}
+	// Output:
+	// 7: [3.7 8.7 2]
+	// 2: [4 8.5 3]
+	// 4: [4.5 8.5 2.5]
+	//
+	// 8: [9.7 2 9]
+	// 9: [10.2 1 9.2]
+	// 1: [10 0 9]
+	// 3: [9.5 0.5 8.5]
+	//
+	// 6: [5 8.5 11]
+	//
+	// 0: [10 8 10]
+	// 5: [10.5 9 12]
+}
+
+// TestDendrogramLCA checks LCA and Ancestor against a naive walk up the
+// parent list.
+func TestDendrogramLCA(t *testing.T) {
+	d := cluster.RandomAdditiveMatrix(30)
+	dg := d.Dendrogram(cluster.DAVG)
+	p := dg.Paths
+	naive := func(a, b graph.NI) graph.NI {
+		for x := a; x >= 0; x = p[x].From {
+			for y := b; y >= 0; y = p[y].From {
+				if x == y {
+					return x
+				}
+			}
+		}
+		return -1
+	}
+	for a := range p {
+		for b := range p {
+			want := naive(graph.NI(a), graph.NI(b))
+			if got := dg.LCA(graph.NI(a), graph.NI(b)); got != want {
+				t.Fatalf("LCA(%d, %d) = %d, want %d", a, b, got, want)
+			}
+			if got := dg.Ancestor(graph.NI(a), graph.NI(b)); got != (want == graph.NI(a)) {
+				t.Fatalf("Ancestor(%d, %d) = %t", a, b, got)
+			}
+		}
+	}
+	if len(dg.LeafOrder()) != dg.NLeaves || dg.NLeaves != len(d) {
+		t.Fatal("leaf count")
+	}
+}
+
+func TestDendrogramCutCopies(t *testing.T) {
+	d := cluster.DistanceMatrix{
+		{0, 4, 8, 8},
+		{4, 0, 8, 8},
+		{8, 8, 0, 6},
+		{8, 8, 6, 0},
+	}
+	dg := cluster.NewDendrogram(d.Ultrametric(cluster.DAVG))
+	order := fmt.Sprint(dg.LeafOrder())
+	cut := fmt.Sprint(dg.Cut(2))
+	for _, clusters := range [][][]graph.NI{dg.Cut(2), dg.CutHeight(3.5)} {
+		for _, c := range clusters {
+			sort.Slice(c, func(i, j int) bool { return c[i] > c[j] })
+			c[0] = -1
+		}
+	}
+	if o := fmt.Sprint(dg.LeafOrder()); o != order {
+		t.Fatal("leaf order modified:", o, "was", order)
+	}
+	if c := fmt.Sprint(dg.Cut(2)); c != cut {
+		t.Fatal("cut", c, "was", cut)
+	}
+}
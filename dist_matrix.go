@@ -4,7 +4,6 @@ package cluster
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -44,6 +43,27 @@ func (d DistanceMatrix) Clone() DistanceMatrix {
 	return dc
 }
 
+// Sub allocates and returns the submatrix of d with rows and columns x.
+//
+// Element [i][j] of the result is d[x[i]][x[j]].  Indexes of x need not be
+// in order so Sub can also be used to reorder a matrix.
+func (d DistanceMatrix) Sub(x []int) DistanceMatrix {
+	return DistanceMatrix(subMatrix(d, x))
+}
+
+func subMatrix(d [][]float64, x []int) [][]float64 {
+	s := make([][]float64, len(x))
+	for i, xi := range x {
+		si := make([]float64, len(x))
+		dxi := d[xi]
+		for j, xj := range x {
+			si[j] = dxi[xj]
+		}
+		s[i] = si
+	}
+	return s
+}
+
 // NewEuclideanDist constructs an n×n distance matrix where n is len(exp)
 // based on Euclidean distance between points.
 func NewEuclideanDist(exp []Point) DistanceMatrix {
@@ -133,29 +153,144 @@ func (d DistanceMatrix) TriangleInequality() (ok bool, i, j, k int) {
 // Conditions are:
 //
 //   * square:  len(d[i]) == len(d)
-//   * symmetric:  d[i][j] == d[j][i]
+//   * not NaN:  d[i][j] is not NaN
 //   * non-negative:  d[i][j] >= 0
+//   * symmetric:  d[i][j] == d[j][i]
 //   * zero diagonal:  d[i][i] == 0
 //   * triangle inequality:  d[i][j] + d[j][k] <= d[i][k]
 //
-// Valid returns nil if all conditions are met, otherwise an error citing
-// a condition not met.
+// Valid returns nil if all conditions are met, otherwise a *ValidationError
+// with a report of violations, as from ValidateEps with tolerance 0.
+// The error unwraps to a *MatrixError citing the first condition not met.
+//
+// The O(n^3) triangle inequality scan is made only if the other conditions
+// are met.  Otherwise the report lists only violations of the other
+// conditions.  Use ValidateEps for a report of all violations.
 func (d DistanceMatrix) Validate() error {
-	if !d.Square() {
-		return errors.New("not square")
+	r := &ValidationReport{}
+	if d.wellFormedEps(r); r.OK() {
+		d.triangleEps(r)
 	}
-	if !d.NonNegative() {
-		return errors.New("negative element")
+	if !r.OK() {
+		return &ValidationError{r}
 	}
-	if !d.Symmetric() {
-		return errors.New("not symmetric")
+	return nil
+}
+
+// MatrixError describes a distance matrix failing a validity condition.
+//
+// Indexes locate the failure.  Their meaning depends on the condition:
+//
+//   * CondSquare:  i, where len(d[i]) != len(d)
+//   * CondNotNaN, CondNonNegative:  i, j of element d[i][j]
+//   * CondSymmetric:  i, j, where d[i][j] != d[j][i]
+//   * CondZeroDiagonal:  i, where d[i][i] != 0
+//   * CondTriangle:  i, j, k, as returned by TriangleInequality
+//   * CondAdditive:  i, j, k, l, as returned by Additive
+//   * CondUltrametric:  i, j, k, as returned by ThreePoint
+//   * CondSize:  n, the number of rows, where too few to build a tree
+//   * CondFinite:  i, j of infinite element d[i][j], rejected by tree builders
+type MatrixError struct {
+	Cond    int // one of the Cond constants
+	Indexes []int
+}
+
+// Cond constants for MatrixError, identifying the condition not met.
+const (
+	CondSquare = iota
+	CondNotNaN
+	CondNonNegative
+	CondSymmetric
+	CondZeroDiagonal
+	CondTriangle
+	CondAdditive
+	CondUltrametric
+	CondSize
+	CondFinite
+	nCond // number of conditions
+)
+
+func (e *MatrixError) Error() string {
+	x := e.Indexes
+	switch e.Cond {
+	case CondSquare:
+		return fmt.Sprintf("not square: len(d[%d]) != len(d)", x[0])
+	case CondNotNaN:
+		return fmt.Sprintf("NaN element: d[%d][%d]", x[0], x[1])
+	case CondNonNegative:
+		return fmt.Sprintf("negative element: d[%d][%d]", x[0], x[1])
+	case CondSymmetric:
+		return fmt.Sprintf("not symmetric: d[%d][%d] != d[%d][%d]",
+			x[0], x[1], x[1], x[0])
+	case CondZeroDiagonal:
+		return fmt.Sprintf("non-zero diagonal: d[%d][%d]", x[0], x[0])
+	case CondTriangle:
+		return fmt.Sprintf("triangle inequality not satisfied: "+
+			"d[%d][%d] + d[%d][%d] < d[%d][%d]",
+			x[0], x[1], x[1], x[2], x[0], x[2])
+	case CondAdditive:
+		return fmt.Sprintf("not additive: "+
+			"four-point condition fails for %d, %d, %d, %d",
+			x[0], x[1], x[2], x[3])
+	case CondUltrametric:
+		return fmt.Sprintf("not ultrametric: "+
+			"three-point condition fails for %d, %d, %d", x[0], x[1], x[2])
+	case CondSize:
+		return fmt.Sprintf("too few rows: %d, need at least 2", x[0])
+	case CondFinite:
+		return fmt.Sprintf("infinite element: d[%d][%d]", x[0], x[1])
+	}
+	return fmt.Sprintf("matrix condition %d not met: %v", e.Cond, x)
+}
+
+// wellFormed checks the conditions of Validate other than the triangle
+// inequality, and that elements are finite.  These are the conditions the
+// tree builders depend on.
+func (d DistanceMatrix) wellFormed() error {
+	for i, di := range d {
+		if len(di) != len(d) {
+			return &MatrixError{CondSquare, []int{i}}
+		}
 	}
-	if !d.ZeroDiagonal() {
-		return errors.New("non-zero diagonal")
+	for i, di := range d {
+		for j, dij := range di {
+			if math.IsNaN(dij) {
+				return &MatrixError{CondNotNaN, []int{i, j}}
+			}
+			if math.IsInf(dij, 0) {
+				return &MatrixError{CondFinite, []int{i, j}}
+			}
+		}
 	}
-	if ok, i, j, k := d.TriangleInequality(); !ok {
-		return fmt.Errorf("triangle inequality not satisfied: "+
-			"d[%d][%d] + d[%d][%d] < d[%d][%d]", i, j, j, k, i, k)
+	for i, di := range d {
+		for j, dij := range di {
+			if dij < 0 {
+				return &MatrixError{CondNonNegative, []int{i, j}}
+			}
+		}
+	}
+	for i, di := range d {
+		for j, dij := range di[:i] {
+			if dij != d[j][i] {
+				return &MatrixError{CondSymmetric, []int{i, j}}
+			}
+		}
+	}
+	for i, di := range d {
+		if di[i] != 0 {
+			return &MatrixError{CondZeroDiagonal, []int{i}}
+		}
+	}
+	return nil
+}
+
+// checkTree checks that d is well formed and large enough to build a tree.
+func (d DistanceMatrix) checkTree() error {
+	if err := d.wellFormed(); err != nil {
+		return err
+	}
+	if len(d) < 2 {
+		return &MatrixError{CondSize, []int{len(d)}}
 	}
 	return nil
 }
@@ -192,6 +327,30 @@ func (d DistanceMatrix) Additive() (ok bool, i, j, k, l int) {
 	return
 }
 
+// ThreePoint tests if DistanceMatrix d is ultrametric.
+//
+// ThreePoint tests the three-point condition for all combinations of
+// points, that for each three points i, j, k, the two largest of d[i][j],
+// d[i][k], and d[j][k] are equal.  This is the condition under which
+// Ultrametric with DAVG or DMIN recovers the tree of the distances.  If a
+// test fails, it returns ok = false and the three failing points.  Use
+// ThreePointEps for a test with tolerance.
+//
+// The test is NaN weak -- the presence of a NaN does not cause the function
+// to return false.
+func (d DistanceMatrix) ThreePoint() (ok bool, i, j, k int) {
+	for i, di := range d {
+		for j, dj := range d[:i] {
+			for k := 0; k < j; k++ {
+				if threePointDev(di[j], di[k], dj[k]) > 0 {
+					return false, i, j, k
+				}
+			}
+		}
+	}
+	return true, 0, 0, 0
+}
+
 // limbWeight finds the weight of the edge to a leaf (a limb) in a phylogenic
 // tree corresponding to DistanceMatrix d.
 //
@@ -269,8 +428,9 @@ func (d DistanceMatrix) limbWeightSubMatrix(j int) (wt float64, i, k int) {
 
 // AdditiveTree constructs an unrooted tree from an additive distance matrix.
 //
-// DistanceMatrix d must be additive.  Use provably additive matrices or
-// use DistanceMatrix.Additive() to verify the additive property.
+// DistanceMatrix d must be additive.  Use provably additive matrices,
+// use DistanceMatrix.Additive() to verify the additive property, or use
+// AdditiveTreeChecked.
 //
 // Result is an unrooted tree, not necessarily binary, as an undirected graph.
 // The first len(d) nodes are the leaves represented by the distance matrix.
@@ -286,8 +446,8 @@ func (d DistanceMatrix) AdditiveTree() (u graph.LabeledUndirected, edgeWts []flo
 	ap = func(n int) {
 		if n == 1 {
 			edgeWts = []float64{d[0][1]}
-			t[0] = []graph.Half{{1, 0}}
-			t[1] = []graph.Half{{0, 0}}
+			t[0] = []graph.Half{{To: 1}}
+			t[1] = []graph.Half{{To: 0}}
 			return
 		}
 		nLen, i, k := d.limbWeightSubMatrix(n)
@@ -331,11 +491,13 @@ func (d DistanceMatrix) AdditiveTree() (u graph.LabeledUndirected, edgeWts []flo
 					for fx, from := range t[to.To] {
 						if from.To == n { // here it is
 							// recycle it to go to v now.
-							t[to.To][fx] = graph.Half{v, y}
+							t[to.To][fx] = graph.Half{To: v, Label: y}
 							break
 						}
 					}
-					t = append(t, []graph.Half{{n, to.Label}, {to.To, y}})
+					t = append(t, []graph.Half{
+						{To: n, Label: to.Label},
+						{To: to.To, Label: y}})
 					x = 0
 					return v
 				default: // continue back out
@@ -349,18 +511,41 @@ func (d DistanceMatrix) AdditiveTree() (u graph.LabeledUndirected, edgeWts []flo
 		v := f(graph.NI(k))
 		y := graph.LI(len(edgeWts))
 		edgeWts = append(edgeWts, nLen)
-		t[n] = []graph.Half{{v, y}}
-		t[v] = append(t[v], graph.Half{graph.NI(n), y})
+		t[n] = []graph.Half{{To: v, Label: y}}
+		t[v] = append(t[v], graph.Half{To: graph.NI(n), Label: y})
 	}
 	ap(len(d) - 1)
-	return graph.LabeledUndirected{t}, edgeWts
+	return graph.LabeledUndirected{LabeledAdjacencyList: t}, edgeWts
+}
+
+// AdditiveTreeChecked is the same as AdditiveTree but checks that d is
+// additive, returning an error rather than building a meaningless tree.
+//
+// Matrix d must pass Validate and Additive, have finite elements, and have
+// at least two rows.
+// Errors with the matrix are returned as *MatrixError.  Note the additive
+// check has time complexity O(n^4).
+func (d DistanceMatrix) AdditiveTreeChecked() (u graph.LabeledUndirected, edgeWts []float64, err error) {
+	if err = d.checkTree(); err != nil {
+		return
+	}
+	if ok, i, j, k := d.TriangleInequality(); !ok {
+		err = &MatrixError{CondTriangle, []int{i, j, k}}
+		return
+	}
+	if ok, i, j, k, l := d.Additive(); !ok {
+		err = &MatrixError{CondAdditive, []int{i, j, k, l}}
+		return
+	}
+	u, edgeWts = d.AdditiveTree()
+	return
 }
 
 // RAMatrix constructs a random additive distance matrix.
 //
 // Argument n is the size of the DistanceMatrix to reutrn.
 func RandomAdditiveMatrix(n int) DistanceMatrix {
-	pl := randomUTree(n)
+	pl := randomUTree(n, rand.Intn)
 	da := make([]struct { // distance annotation of parent list
 		leng int     // path length
 		wt   float64 // edge weight to parent
@@ -443,11 +628,30 @@ const (
 // in the list.  Having no logical parent, the root will have parent = -1 and
 // Weight = NaN.  It will also have NLeaves = len(dm).
 //
-// See also UltrametricD.
+// See also UltrametricD and UltrametricChecked.
 func (dm DistanceMatrix) Ultrametric(cdf int) (graph.FromList, []Ultrametric) {
 	return dm.Clone().UltrametricD(cdf)
 }
 
+// UltrametricChecked is the same as Ultrametric but checks its arguments,
+// returning an error rather than panicking.
+//
+// Argument cdf must be DAVG or DMIN.  DistanceMatrix dm must pass the
+// conditions of Validate other than the triangle inequality, have finite
+// elements, and have at least two rows.  Errors with the matrix are
+// returned as *MatrixError.  The receiver is not modified.
+func (dm DistanceMatrix) UltrametricChecked(cdf int) (graph.FromList, []Ultrametric, error) {
+	if cdf != DAVG && cdf != DMIN {
+		return graph.FromList{}, nil,
+			fmt.Errorf("invalid cluster distance function %d", cdf)
+	}
+	if err := dm.checkTree(); err != nil {
+		return graph.FromList{}, nil, err
+	}
+	pl, ul := dm.Ultrametric(cdf)
+	return pl, ul, nil
+}
+
 // UltrametricD is the same as Ultrametric but is destructive on the receiver.
 //
 // It saves a little memory if you have no further use for the distance matrix.
@@ -573,37 +777,6 @@ func (dm DistanceMatrix) closest(clusters []int) (iMin, jMin, cj int) {
 	return
 }
 
-// Cut partitions leaf nodes of an ultrametric tree into k clusters.
-//
-// A UList of length l represents a tree with nLeaves = (l+1)/2 leaves.
-// Cut returns an k-partition of 0:nLeaves.  Each partition corresponds to
-// a subtree of u.  Because of the increasing age property of a UList,
-// the parents of the roots of the k subtrees will be the last k-1
-// elements of the list, that is the parents will be >= len(u)-(k-1).
-/*
-func (u UList) Cut(k int) (clusters [][]int) {
-	nLeaves := (len(u) + 1) / 2
-	if k > nLeaves {
-		k = nLeaves
-	}
-	clusters = make([][]int, 0, k) // return value has k clusters
-	cut := len(u) - (k - 1)
-	c := make([][]int, cut) // working data uses more though
-	for l := range c[:nLeaves] {
-		c[l] = []int{l}
-	}
-	for i, ui := range u[:cut] {
-		uiP := ui.Parent
-		if uiP >= cut {
-			clusters = append(clusters, c[i])
-		} else {
-			c[uiP] = append(c[uiP], c[i]...)
-		}
-	}
-	return
-}
-*/
-
 // NeighborJoin constructs an unrooted tree from a distance matrix using the
 // neighbor joining algorithm.
 //
@@ -611,11 +784,26 @@ func (u UList) Cut(k int) (clusters [][]int) {
 // the graph are labeled as indexes into the weight list.  Leaves of the
 // the tree will be graph node 0:len(dm).
 //
-// See also NeighborJoinD.
+// See also NeighborJoinD and NeighborJoinChecked.
 func (dm DistanceMatrix) NeighborJoin() (u graph.LabeledUndirected, wt []float64) {
 	return dm.Clone().NeighborJoinD()
 }
 
+// NeighborJoinChecked is the same as NeighborJoin but checks the receiver,
+// returning an error rather than panicking.
+//
+// DistanceMatrix dm must pass the conditions of Validate other than the
+// triangle inequality, have finite elements, and have at least two rows.
+// Errors with the matrix are returned as *MatrixError.  The receiver is not
+// modified.
+func (dm DistanceMatrix) NeighborJoinChecked() (u graph.LabeledUndirected, wt []float64, err error) {
+	if err = dm.checkTree(); err != nil {
+		return
+	}
+	u, wt = dm.NeighborJoin()
+	return
+}
+
 // NeighborJoinD is the same as NeighborJoin but is destructive on the receiver.
 //
 // It saves a little memory if you have no further use for the distance matrix.
@@ -682,7 +870,7 @@ func (dm DistanceMatrix) NeighborJoinD() (u graph.LabeledUndirected, wt []float6
 		for j, dij := range di1 {
 			mn := .5 * (dij + di2[j] - d21)
 			if j == d1 && mn != 0 {
-				panic("uh uh, prolly skip this one...")
+				panic("NeighborJoin: non-zero diagonal")
 			}
 			di1[j] = mn
 			dm[j][d1] = mn
@@ -707,12 +895,12 @@ func (dm DistanceMatrix) NeighborJoinD() (u graph.LabeledUndirected, wt []float6
 		wx2 := wx1 + 1
 		wt = append(wt, ll1, ll2)
 		tree[m] = append(tree[m],
-			graph.Half{n1, wx1},
-			graph.Half{n2, wx2})
-		tree[n1] = append(tree[n1], graph.Half{m, wx1})
-		tree[n2] = append(tree[n2], graph.Half{m, wx2})
+			graph.Half{To: n1, Label: wx1},
+			graph.Half{To: n2, Label: wx2})
+		tree[n1] = append(tree[n1], graph.Half{To: m, Label: wx1})
+		tree[n2] = append(tree[n2], graph.Half{To: m, Label: wx2})
 		return
 	}
 	nj(graph.NI(len(dm)))
-	return graph.LabeledUndirected{tree}, wt
+	return graph.LabeledUndirected{LabeledAdjacencyList: tree}, wt
 }
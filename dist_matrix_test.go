@@ -3,6 +3,7 @@
 package cluster_test
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"testing"
@@ -176,6 +177,96 @@ func ExampleDistanceMatrix_Validate() {
 	// triangle inequality not satisfied: d[1][3] + d[3][0] < d[1][0]
 }
 
+func ExampleMatrixError() {
+	d := cluster.DistanceMatrix{
+		{0, 4, 6},
+		{4, 0, math.NaN()},
+		{6, math.NaN(), 0},
+	}
+	_, _, err := d.NeighborJoinChecked()
+	fmt.Println(err)
+	var me *cluster.MatrixError
+	if errors.As(err, &me) {
+		fmt.Println(me.Cond == cluster.CondNotNaN, me.Indexes)
+	}
+	// Output:
+	// NaN element: d[1][2]
+	// true [1 2]
+}
+
+func ExampleDistanceMatrix_AdditiveTreeChecked() {
+	na := cluster.DistanceMatrix{
+		{0, 3, 4, 3},
+		{3, 0, 4, 5},
+		{4, 4, 0, 2},
+		{3, 5, 2, 0},
+	}
+	_, _, err := na.AdditiveTreeChecked()
+	fmt.Println(err)
+	// Output:
+	// not additive: four-point condition fails for 3, 1, 0, 2
+}
+
+func TestCheckedBuilders(t *testing.T) {
+	for _, tc := range []struct {
+		d    cluster.DistanceMatrix
+		cond int
+		x    []int
+	}{
+		{cluster.DistanceMatrix{{0, 1}, {1}}, cluster.CondSquare, []int{1}},
+		{cluster.DistanceMatrix{{0, -1}, {-1, 0}}, cluster.CondNonNegative, []int{0, 1}},
+		{cluster.DistanceMatrix{{0, 1}, {2, 0}}, cluster.CondSymmetric, []int{1, 0}},
+		{cluster.DistanceMatrix{{0, 1}, {1, 3}}, cluster.CondZeroDiagonal, []int{1}},
+		{cluster.DistanceMatrix{{0}}, cluster.CondSize, []int{1}},
+		{cluster.DistanceMatrix{{0, 1, 2}, {1, 0, math.Inf(1)}, {2, math.Inf(1), 0}},
+			cluster.CondFinite, []int{1, 2}},
+		{cluster.DistanceMatrix{
+			{0, math.Inf(1), math.Inf(1)},
+			{math.Inf(1), 0, math.Inf(1)},
+			{math.Inf(1), math.Inf(1), 0}}, cluster.CondFinite, []int{0, 1}},
+		{cluster.DistanceMatrix{{0, math.Inf(-1)}, {math.Inf(-1), 0}},
+			cluster.CondFinite, []int{0, 1}},
+	} {
+		errs := make([]error, 3)
+		_, _, errs[0] = tc.d.UltrametricChecked(cluster.DAVG)
+		_, _, errs[1] = tc.d.NeighborJoinChecked()
+		_, _, errs[2] = tc.d.AdditiveTreeChecked()
+		for _, err := range errs {
+			me, ok := err.(*cluster.MatrixError)
+			if !ok || me.Cond != tc.cond || fmt.Sprint(me.Indexes) != fmt.Sprint(tc.x) {
+				t.Fatalf("%v: got %v, want condition %d at %v", tc.d, err, tc.cond, tc.x)
+			}
+		}
+	}
+	d := cluster.DistanceMatrix{{0}}
+	if _, _, err := d.NeighborJoinChecked(); err == nil {
+		t.Fatal("1x1 matrix accepted")
+	}
+	if _, _, err := (cluster.DistanceMatrix{{0, 1}, {1, 0}}).UltrametricChecked(2); err == nil {
+		t.Fatal("invalid cdf accepted")
+	}
+}
+
+func ExampleDistanceMatrix_ThreePoint() {
+	u := cluster.DistanceMatrix{
+		{0, 4, 8, 8},
+		{4, 0, 8, 8},
+		{8, 8, 0, 6},
+		{8, 8, 6, 0},
+	}
+	nu := cluster.DistanceMatrix{
+		{0, 4, 8, 8},
+		{4, 0, 7, 8},
+		{8, 7, 0, 6},
+		{8, 8, 6, 0},
+	}
+	fmt.Println(u.ThreePoint())
+	fmt.Println(nu.ThreePoint())
+	// Output:
+	// true 0 0 0
+	// false 2 1 0
+}
+
 func ExampleDistanceMatrix_Additive() {
 	a := cluster.DistanceMatrix{
 		{0, 13, 21, 22},
@@ -285,42 +376,3 @@ func ExampleDistanceMatrix_NeighborJoin() {
 	// 5   0    8.000
 	// 5   4    2.000
 }
-
-/*
-func ExampleUList_Cut() {
-	exp := []cluster.Point{
-		{10, 8, 10},
-		{10, 0, 9},
-		{4, 8.5, 3},
-		{9.5, .5, 8.5},
-		{4.5, 8.5, 2.5},
-		{10.5, 9, 12},
-		{5, 8.5, 11},
-		{3.7, 8.7, 2},
-		{9.7, 2, 9},
-		{10.2, 1, 9.2},
-	}
-	dm := cluster.NewEuclideanDist(exp)
-	u := dm.Ultrametric(cluster.DAVG)
-	for _, c := range u.Cut(4) {
-		for _, x := range c {
-			fmt.Printf("%d: %g\n", x, exp[x])
-		}
-		fmt.Println()
-	}
-	// Output:
-	// 6: [5 8.5 11]
-	//
-	// 7: [3.7 8.7 2]
-	// 2: [4 8.5 3]
-	// 4: [4.5 8.5 2.5]
-	//
-	// 8: [9.7 2 9]
-	// 9: [10.2 1 9.2]
-	// 1: [10 0 9]
-	// 3: [9.5 0.5 8.5]
-	//
-	// 0: [10 8 10]
-	// 5: [10.5 9 12]
-}
-*/
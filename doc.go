@@ -8,6 +8,10 @@
 // K-Means operates on an N-dimensional point type.  Three initializers are
 // provided, "++", random, and first points in list.  A K-means++ wrapper is
 // provided as a convenience.
+// Points can be obtained from a distance matrix by classical
+// multidimensional scaling or by SMACOF stress majorization.
+// Points can be preprocessed by fitted transforms, z-score, min-max,
+// log1p, quantile normalization, and PCA, that can be saved and reapplied.
 //
 // Expectation Maximization
 //
@@ -20,6 +24,31 @@
 // The method Ultrametric can perform either UPGMA or
 // single-linkage clustering and produce a rooted ultrametric tree.
 // Methods AdditiveTree and NeighborJoin produce unrooted binary trees.
+// NeighborJoinFast and RapidNJ are faster, iterative implementations of
+// neighbor joining.  BIONJ and UNJ are variants of neighbor joining.
+// Checked variants of Ultrametric, NeighborJoin, and AdditiveTree validate
+// the matrix and return errors rather than panicking.
+// Least squares, including Fitch-Margoliash weighting, fits branch lengths
+// of a tree to a matrix that need not be additive, and guides a search for
+// the best fitting tree.
+// FourPointDeviation scores a matrix's departure from additivity over
+// quartets, and NearestAdditive projects it to the distances of a least
+// squares tree for use with AdditiveTree.
+// MinimumEvolution improves a neighbor joining tree by balanced minimum
+// evolution with nearest neighbor interchange and subtree prune and regraft.
+// A Dendrogram type wraps the rooted tree with indexes for traversal,
+// leaf order, lowest common ancestor queries, and cuts into clusters.
+// Optimal leaf ordering flips dendrogram children to minimize the distance
+// between adjacent leaves.
+// Trees can be written and read in Newick format, and a patristic distance
+// matrix computed from a tree.
+// Unrooted trees can be rooted at their midpoint or by an outgroup.
+// Trees can be compared by their splits with the Robinson-Foulds and
+// branch score distances.
+// Bootstrap support for tree splits is computed from replicate matrices
+// in parallel.
+// Strict, majority-rule, and extended majority-rule consensus trees
+// summarize many trees.
 //
 // Clique approximation
 //
@@ -32,5 +61,18 @@
 // A few methods are exported for the Point type, including a Pearson
 // correlation coefficient function useful for constructing similarity
 // matrices.  Also some data validation methods, a random tree generator
-// and a random distance matrix generator.
+// and a random distance matrix generator.  For simulation there are
+// generators of random unrooted topologies, Yule and coalescent trees, and
+// ultrametric and noisy additive matrices taking an explicit rand.Rand.
+// Validation with a tolerance reports all violations found.  ThreePoint
+// tests the ultrametric condition under which UPGMA recovers a tree.
+// MetricNearness and MetricClosure repair violations of the triangle
+// inequality.  Impute fills missing distances, given as NaN, by row means,
+// shortest paths, or the four-point or three-point conditions.
+// Distance matrices can be read and written in PHYLIP format.
+// Evolutionary distances can be computed from aligned sequences read in
+// FASTA or PHYLIP format.  Hamming and edit distances between unaligned
+// strings are computed in parallel.
+// Labeled distance and similarity matrices carry sample names through
+// tree building and clustering.
 package cluster
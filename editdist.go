@@ -0,0 +1,215 @@
+// Public domain.
+
+package cluster
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// EditHamming, EditLevenshtein, EditDamerau constants for the metric
+// argument of NewEditDist.
+const (
+	EditHamming     = iota // substitutions, strings of equal length only
+	EditLevenshtein        // insertions, deletions, substitutions
+	EditDamerau            // as Levenshtein, plus adjacent transpositions
+)
+
+// NewEditDist constructs an n×n distance matrix where n is len(s) using
+// an edit distance between strings.
+//
+// Strings are compared byte by byte.  With metric EditHamming the distance
+// is the number of positions at which strings differ.  All strings must then
+// be the same length, otherwise an error is returned.  With EditLevenshtein
+// it is the minimum number of single byte insertions, deletions, and
+// substitutions transforming one string into the other.  EditDamerau also
+// allows transposition of two adjacent bytes.  It is the restricted form,
+// or optimal string alignment distance, where no substring is edited more
+// than once.  Unlike the other metrics it does not always satisfy the
+// triangle inequality.
+//
+// Argument k, if non-negative, is a threshold.  Distances greater than k
+// are not computed exactly but are reported as k+1.  For Levenshtein and
+// Damerau distances the computation is then limited to a band of width
+// 2k+1 around the diagonal and stops early once the distance is known to
+// exceed k, taking O(k m) time rather than O(m^2) for strings of length m.
+// A negative k computes all distances exactly.
+//
+// Distances between pairs are computed in parallel, with up to GOMAXPROCS
+// goroutines.
+func NewEditDist(s []string, metric, k int) (DistanceMatrix, error) {
+	b := make([][]byte, len(s))
+	for i, si := range s {
+		b[i] = []byte(si)
+	}
+	return NewEditDistBytes(b, metric, k)
+}
+
+// NewEditDistBytes constructs an n×n distance matrix where n is len(b)
+// using an edit distance between byte strings.
+//
+// See NewEditDist.
+func NewEditDistBytes(b [][]byte, metric, k int) (DistanceMatrix, error) {
+	switch metric {
+	case EditHamming:
+		for i, bi := range b {
+			if len(bi) != len(b[0]) {
+				return nil, fmt.Errorf(
+					"Hamming distance: string %d has length %d, expected %d",
+					i, len(bi), len(b[0]))
+			}
+		}
+		return parallelDist(len(b), func() func(i, j int) float64 {
+			return func(i, j int) float64 {
+				return float64(hamming(b[i], b[j], k))
+			}
+		}), nil
+	case EditLevenshtein, EditDamerau:
+		return parallelDist(len(b), func() func(i, j int) float64 {
+			var e editRows
+			return func(i, j int) float64 {
+				return float64(e.dist(b[i], b[j], k, metric == EditDamerau))
+			}
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown edit distance metric %d", metric)
+}
+
+// parallelDist constructs an n×n symmetric distance matrix with a zero
+// diagonal, computing elements i > j with functions returned by newDist.
+// Rows are distributed over up to GOMAXPROCS goroutines, each calling
+// newDist once.
+func parallelDist(n int, newDist func() func(i, j int) float64) DistanceMatrix {
+	dist := make(DistanceMatrix, n)
+	for i := range dist {
+		dist[i] = make([]float64, n)
+	}
+	var wg sync.WaitGroup
+	next := make(chan int)
+	nw := runtime.GOMAXPROCS(0)
+	if nw > n {
+		nw = n
+	}
+	for w := 0; w < nw; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f := newDist()
+			for i := range next {
+				di := dist[i]
+				for j := 0; j < i; j++ {
+					d := f(i, j)
+					di[j] = d
+					dist[j][i] = d
+				}
+			}
+		}()
+	}
+	// long rows first, for better balance
+	for i := n - 1; i > 0; i-- {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return dist
+}
+
+// hamming returns the number of differing positions of a and b, which must
+// be the same length, or k+1 if that is greater than non-negative k.
+func hamming(a, b []byte, k int) int {
+	d := 0
+	for i, ai := range a {
+		if ai != b[i] {
+			d++
+			if d == k+1 {
+				break
+			}
+		}
+	}
+	return d
+}
+
+// editRows holds dynamic programming rows for reuse between calls of dist.
+type editRows struct {
+	p2, p, c []int
+}
+
+// dist returns the Levenshtein distance, or with damerau the optimal string
+// alignment distance, between a and b, or k+1 if that is greater than
+// non-negative k.
+func (e *editRows) dist(a, b []byte, k int, damerau bool) int {
+	la, lb := len(a), len(b)
+	m := la
+	if lb > m {
+		m = lb
+	}
+	if k < 0 || k > m {
+		k = m // the distance cannot exceed m
+	}
+	if la-lb > k || lb-la > k {
+		return k + 1
+	}
+	big := k + 1
+	if cap(e.c) < lb+1 {
+		e.p2 = make([]int, lb+1)
+		e.p = make([]int, lb+1)
+		e.c = make([]int, lb+1)
+	}
+	p2, p, c := e.p2[:lb+1], e.p[:lb+1], e.c[:lb+1]
+	for j := range p {
+		p[j] = big
+		if j <= k {
+			p[j] = j
+		}
+	}
+	// row i holds distances of a[:i] to b[:j] for j in the band
+	// lo-1 <= j <= hi+1, where the band limits hold big.
+	for i := 1; i <= la; i++ {
+		lo, hi := 1, lb
+		if i-k > lo {
+			lo = i - k
+		}
+		if i+k < hi {
+			hi = i + k
+		}
+		c[lo-1] = big
+		if lo == 1 && i <= k {
+			c[0] = i
+		}
+		rowMin := c[lo-1]
+		ai := a[i-1]
+		for j := lo; j <= hi; j++ {
+			d := p[j-1]
+			if ai != b[j-1] {
+				d++
+			}
+			if x := p[j] + 1; x < d {
+				d = x
+			}
+			if x := c[j-1] + 1; x < d {
+				d = x
+			}
+			if damerau && i > 1 && j > 1 && ai == b[j-2] && a[i-2] == b[j-1] {
+				if x := p2[j-2] + 1; x < d {
+					d = x
+				}
+			}
+			if d > big {
+				d = big
+			}
+			c[j] = d
+			if d < rowMin {
+				rowMin = d
+			}
+		}
+		if hi < lb {
+			c[hi+1] = big
+		}
+		if rowMin > k {
+			return big
+		}
+		p2, p, c = p, c, p2
+	}
+	return p[lb]
+}
@@ -0,0 +1,144 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleNewEditDist() {
+	s := []string{"kitten", "sitting", "kitchen", "ktiten"}
+	for _, metric := range []int{cluster.EditLevenshtein, cluster.EditDamerau} {
+		d, _ := cluster.NewEditDist(s, metric, -1)
+		fmt.Println(d)
+		fmt.Println()
+	}
+	// with a threshold, larger distances are reported as k+1
+	d, _ := cluster.NewEditDist(s, cluster.EditLevenshtein, 2)
+	fmt.Println(d)
+	// Output:
+	// [0 3 2 2]
+	// [3 0 5 5]
+	// [2 5 0 3]
+	// [2 5 3 0]
+	//
+	// [0 3 2 1]
+	// [3 0 5 4]
+	// [2 5 0 3]
+	// [1 4 3 0]
+	//
+	// [0 3 2 2]
+	// [3 0 3 3]
+	// [2 3 0 3]
+	// [2 3 3 0]
+}
+
+func ExampleDistanceMatrix_Similarity() {
+	barcodes := []string{
+		"ACGTACGT",
+		"ACGTACGA",
+		"ACGAACGT",
+		"TTGCATGC",
+		"TTGCATCC",
+		"TTGCATGG",
+	}
+	d, err := cluster.NewEditDist(barcodes, cluster.EditHamming, 3)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// distance at most 2 is similarity at least 2
+	c := d.Similarity(4).CAST(1.5)
+	for _, ci := range c {
+		sort.Ints(ci)
+	}
+	sort.Slice(c, func(i, j int) bool { return c[i][0] < c[j][0] })
+	fmt.Println(c)
+	// Output:
+	// [[0 1 2] [3 4 5]]
+}
+
+// editDist is a straightforward full matrix optimal string alignment
+// distance, for reference.
+func editDist(a, b string, damerau bool) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	min := func(x, y int) int {
+		if x < y {
+			return x
+		}
+		return y
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			c := 1
+			if a[i-1] == b[j-1] {
+				c = 0
+			}
+			d[i][j] = min(d[i-1][j-1]+c, min(d[i-1][j], d[i][j-1])+1)
+			if damerau && i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func TestNewEditDistBanded(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	s := make([]string, 40)
+	for i := range s {
+		b := make([]byte, r.Intn(12))
+		for j := range b {
+			b[j] = "ACG"[r.Intn(3)]
+		}
+		s[i] = string(b)
+	}
+	for _, metric := range []int{cluster.EditLevenshtein, cluster.EditDamerau} {
+		for _, k := range []int{-1, 0, 1, 2, 3, 5, 8, 20} {
+			d, err := cluster.NewEditDist(s, metric, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range s {
+				for j := range s {
+					want := editDist(s[i], s[j], metric == cluster.EditDamerau)
+					if k >= 0 && want > k {
+						want = k + 1
+					}
+					if d[i][j] != float64(want) {
+						t.Fatalf("metric %d, k %d, %q %q: got %g, want %d",
+							metric, k, s[i], s[j], d[i][j], want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNewEditDistHamming(t *testing.T) {
+	d, err := cluster.NewEditDistBytes([][]byte{
+		[]byte("AAAA"), []byte("AAAT"), []byte("TTTT")},
+		cluster.EditHamming, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(d) != "[0 1 3]\n[1 0 3]\n[3 3 0]" {
+		t.Fatal(d)
+	}
+	if _, err := cluster.NewEditDist([]string{"AA", "A"},
+		cluster.EditHamming, -1); err == nil {
+		t.Fatal("unequal lengths accepted")
+	}
+}
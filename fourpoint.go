@@ -0,0 +1,128 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/soniakeys/graph"
+)
+
+// FourPointDeviation measures the deviation of d from additivity over
+// quartets of points.
+//
+// For each quartet i, j, k, l, let s1 >= s2 >= s3 be the sums
+// d[i][j] + d[k][l], d[i][k] + d[j][l], and d[i][l] + d[j][k].  The
+// four-point condition holds when s1 = s2.  The deviation of the quartet is
+// the delta score of Holland et al., "Delta plots: a tool for analyzing
+// phylogenetic distance data," 2002, (s1 - s2) / (s1 - s3), which ranges
+// from 0 for a quartet fitting a tree to 1 for a quartet with no tree-like
+// signal.  Quartets with s1 = s3 have deviation 0.
+//
+// If the number of quartets n(n-1)(n-2)(n-3)/24 is no more than
+// maxQuartets, or if maxQuartets <= 0, all quartets are evaluated.
+// Otherwise maxQuartets quartets are sampled at random using rnd, or the
+// math/rand default source if rnd is nil.
+//
+// Returned are the deviations of the quartets evaluated, sorted in
+// increasing order, and their mean.  The sorted deviations give the
+// distribution of the score; for example delta[len(delta)/2] is the median.
+func (d DistanceMatrix) FourPointDeviation(maxQuartets int, rnd *rand.Rand) (delta []float64, mean float64) {
+	n := len(d)
+	if n < 4 {
+		return nil, 0
+	}
+	q := func(i, j, k, l int) float64 {
+		s1 := d[i][j] + d[k][l]
+		s2 := d[i][k] + d[j][l]
+		s3 := d[i][l] + d[j][k]
+		if s1 < s2 {
+			s1, s2 = s2, s1
+		}
+		if s2 < s3 {
+			s2, s3 = s3, s2
+			if s1 < s2 {
+				s1, s2 = s2, s1
+			}
+		}
+		if s1 == s3 {
+			return 0
+		}
+		return (s1 - s2) / (s1 - s3)
+	}
+	nf := float64(n)
+	if all := nf * (nf - 1) * (nf - 2) * (nf - 3) / 24; maxQuartets <= 0 ||
+		all <= float64(maxQuartets) {
+		delta = make([]float64, 0, int(all))
+		for i := 0; i < n; i++ {
+			for j := 0; j < i; j++ {
+				for k := 0; k < j; k++ {
+					for l := 0; l < k; l++ {
+						delta = append(delta, q(i, j, k, l))
+					}
+				}
+			}
+		}
+	} else {
+		intn := rand.Intn
+		if rnd != nil {
+			intn = rnd.Intn
+		}
+		delta = make([]float64, maxQuartets)
+		var x [4]int
+		for s := range delta {
+			for c := 0; c < len(x); {
+				x[c] = intn(n)
+				c++
+				for _, p := range x[:c-1] {
+					if p == x[c-1] {
+						c-- // draw again
+						break
+					}
+				}
+			}
+			delta[s] = q(x[0], x[1], x[2], x[3])
+		}
+	}
+	sort.Float64s(delta)
+	for _, x := range delta {
+		mean += x
+	}
+	return delta, mean / float64(len(delta))
+}
+
+// NearestAdditive returns an additive matrix near d.
+//
+// The matrix is that of the tree found by LeastSquaresTree with the given
+// power, with any negative branch lengths then set to zero.  The result
+// holds the distances between leaves of this tree, which is also returned
+// in the representation of NeighborJoin.  The result is symmetric with a
+// zero diagonal and satisfies the triangle inequality, and, up to floating
+// point rounding, the four-point condition.  Use AdditiveEps rather than
+// Additive to test the result.
+//
+// Branch lengths are not constrained during the fit.  Where the fit gives
+// negative lengths, the clipped result is not in general the least squares
+// additive matrix nearest d, and a tree built from it, as by AdditiveTree,
+// gives the same leaf distances but may resolve zero length edges
+// differently.
+//
+// Argument d must pass the conditions of Validate other than the triangle
+// inequality, and have at least two rows.  The receiver is not modified.
+// See LeastSquaresTree for time complexity.
+func (d DistanceMatrix) NearestAdditive(power float64) (a DistanceMatrix, u graph.LabeledUndirected, wt []float64) {
+	u, wt, _ = d.LeastSquaresTree(power)
+	for i, w := range wt {
+		if w < 0 {
+			wt[i] = 0
+		}
+	}
+	a = NewPatristicDist(u, wt, len(d))
+	for i, ai := range a {
+		for j := 0; j < i; j++ {
+			a[j][i] = ai[j] // symmetric despite rounding
+		}
+	}
+	return
+}
@@ -0,0 +1,81 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleDistanceMatrix_FourPointDeviation() {
+	rnd := rand.New(rand.NewSource(3))
+	for _, noise := range []float64{0, .1, .5} {
+		d, _, _ := cluster.RandomNoisyAdditiveMatrix(12, noise, rnd)
+		delta, mean := d.FourPointDeviation(0, nil)
+		fmt.Printf("noise %.1f: %d quartets, median %.3f, mean %.3f\n",
+			noise, len(delta), delta[len(delta)/2], mean)
+	}
+	// Output:
+	// noise 0.0: 495 quartets, median 0.000, mean 0.000
+	// noise 0.1: 495 quartets, median 0.231, mean 0.283
+	// noise 0.5: 495 quartets, median 0.552, mean 0.548
+}
+
+func ExampleDistanceMatrix_NearestAdditive() {
+	d := cluster.DistanceMatrix{
+		{0, 5, 10, 9, 8},
+		{5, 0, 10, 10, 8},
+		{10, 10, 0, 8, 7},
+		{9, 10, 8, 0, 3},
+		{8, 8, 7, 3, 0},
+	}
+	fmt.Println(d.AdditiveEps(1e-9).Count[cluster.CondAdditive], "violations")
+	a, _, _ := d.NearestAdditive(cluster.OLS)
+	fmt.Println(a.AdditiveEps(1e-9).Count[cluster.CondAdditive], "violations")
+	for _, ai := range a {
+		fmt.Printf("%6.3f\n", ai)
+	}
+	// Output:
+	// 3 violations
+	// 0 violations
+	// [ 0.000  5.000  9.833  9.250  7.917]
+	// [ 5.000  0.000 10.167  9.583  8.250]
+	// [ 9.833 10.167  0.000  8.167  6.833]
+	// [ 9.250  9.583  8.167  0.000  3.000]
+	// [ 7.917  8.250  6.833  3.000  0.000]
+}
+
+func TestFourPointDeviationSampled(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	d, _, _ := cluster.RandomNoisyAdditiveMatrix(30, .3, rnd)
+	all, mAll := d.FourPointDeviation(0, nil)
+	if len(all) != 30*29*28*27/24 {
+		t.Fatal(len(all))
+	}
+	s, mS := d.FourPointDeviation(5000, rnd)
+	if len(s) != 5000 {
+		t.Fatal(len(s))
+	}
+	if mS < mAll*.9 || mS > mAll*1.1 {
+		t.Fatal("sampled mean", mS, "full mean", mAll)
+	}
+	if ok, _, _, _, _ := d.Additive(); ok {
+		t.Fatal("noisy matrix additive")
+	}
+	n, u, wt := d.NearestAdditive(cluster.OLS)
+	if r := n.ValidateEps(1e-9); !r.OK() {
+		t.Fatal(r)
+	}
+	if r := n.AdditiveEps(1e-9); !r.OK() {
+		t.Fatal(r)
+	}
+	// AdditiveTree recovers the tree, apart from zero length edges
+	u2, wt2 := n.AdditiveTree()
+	if bs := cluster.BranchScore(cluster.Bipartitions(u, wt, 30),
+		cluster.Bipartitions(u2, wt2, 30)); bs > 1e-6 {
+		t.Fatal("branch score", bs)
+	}
+}
@@ -1,3 +1,8 @@
-module "github.com/soniakeys/cluster"
+module github.com/soniakeys/cluster
 
-require "github.com/soniakeys/graph" v0.0.0
+go 1.27.1
+
+require (
+	github.com/soniakeys/bits v1.0.0
+	github.com/soniakeys/graph v0.0.0
+)
@@ -0,0 +1,190 @@
+// Public domain.
+
+package cluster
+
+import "math"
+
+// ImputeMean, ImputePath, ImputeAdditive, ImputeUltrametric constants for
+// the method argument of Impute.
+const (
+	ImputeMean        = iota // mean of the rows
+	ImputePath               // shortest path over known distances
+	ImputeAdditive           // four-point condition
+	ImputeUltrametric        // three-point condition
+)
+
+// Impute estimates missing distances, elements of d that are NaN.
+//
+// Diagonal elements that are NaN are set to 0.  An off-diagonal element
+// that is NaN where its transpose is not takes the value of the transpose.
+// Remaining missing distances are estimated by method:
+//
+//   - ImputeMean:  the average of the means of the known off-diagonal
+//     distances in rows i and j, or the mean of all known distances if both
+//     rows have none.
+//   - ImputePath:  the length of the shortest path between i and j over
+//     known distances, the least upper bound allowed by the triangle
+//     inequality.
+//   - ImputeAdditive:  the least estimate max(d[i][k] + d[j][l],
+//     d[i][l] + d[j][k]) - d[k][l] from the four-point condition over
+//     points k and l with known distances.  For an additive matrix the
+//     estimate is exact if there are such k and l not separated from i and
+//     j by the tree.
+//   - ImputeUltrametric:  the least estimate max(d[i][k], d[j][k]) from the
+//     three-point condition over points k with known distances.  For an
+//     ultrametric matrix the estimate is exact if there is such k nearer to
+//     i or to j than i and j are to each other.
+//
+// With ImputeAdditive and ImputeUltrametric, distances are estimated in
+// rounds where each round uses the known and previously estimated distances.
+// Rounds continue as long as some distance can be estimated.
+//
+// The receiver is not modified.  Returned are the matrix with missing
+// distances filled and the cells filled, as index pairs i >= j.  Where a
+// distance cannot be estimated, as with ImputePath for points not connected
+// by known distances, it is left NaN and not listed.
+func (d DistanceMatrix) Impute(method int) (DistanceMatrix, [][2]int) {
+	r := d.Clone()
+	var filled [][2]int
+	var missing [][2]int
+	for i, ri := range r {
+		if math.IsNaN(ri[i]) {
+			ri[i] = 0
+			filled = append(filled, [2]int{i, i})
+		}
+		for j, rij := range ri[:i] {
+			switch rji := r[j][i]; {
+			case !math.IsNaN(rij) && !math.IsNaN(rji):
+			case !math.IsNaN(rij):
+				r[j][i] = rij
+				filled = append(filled, [2]int{i, j})
+			case !math.IsNaN(rji):
+				ri[j] = rji
+				filled = append(filled, [2]int{i, j})
+			default:
+				missing = append(missing, [2]int{i, j})
+			}
+		}
+	}
+	set := func(c [2]int, x float64) {
+		r[c[0]][c[1]] = x
+		r[c[1]][c[0]] = x
+		filled = append(filled, c)
+	}
+	switch method {
+	case ImputeMean:
+		r.imputeMean(missing, set)
+	case ImputePath:
+		p := r.Clone()
+		for _, pi := range p {
+			for j, pij := range pi {
+				if math.IsNaN(pij) {
+					pi[j] = math.Inf(1)
+				}
+			}
+		}
+		p.closure()
+		for _, c := range missing {
+			if x := p[c[0]][c[1]]; !math.IsInf(x, 1) {
+				set(c, x)
+			}
+		}
+	case ImputeAdditive, ImputeUltrametric:
+		est := r.fourPointEst
+		if method == ImputeUltrametric {
+			est = r.threePointEst
+		}
+		for len(missing) > 0 {
+			var next [][2]int // still missing
+			var x []float64
+			for _, c := range missing {
+				x = append(x, est(c[0], c[1]))
+			}
+			for m, c := range missing {
+				if math.IsInf(x[m], 1) {
+					next = append(next, c)
+				} else {
+					set(c, x[m])
+				}
+			}
+			if len(next) == len(missing) {
+				break
+			}
+			missing = next
+		}
+	}
+	return r, filled
+}
+
+// imputeMean fills missing cells of d with averages of row means.
+func (d DistanceMatrix) imputeMean(missing [][2]int, set func([2]int, float64)) {
+	mean := make([]float64, len(d)) // row means, NaN for no known distances
+	sum, n := 0., 0
+	for i, di := range d {
+		s, m := 0., 0
+		for j, dij := range di {
+			if j != i && !math.IsNaN(dij) {
+				s += dij
+				m++
+			}
+		}
+		mean[i] = s / float64(m)
+		sum += s
+		n += m
+	}
+	all := sum / float64(n)
+	for _, c := range missing {
+		mi, mj := mean[c[0]], mean[c[1]]
+		switch {
+		case math.IsNaN(mi) && math.IsNaN(mj):
+			if !math.IsNaN(all) {
+				set(c, all)
+			}
+		case math.IsNaN(mi):
+			set(c, mj)
+		case math.IsNaN(mj):
+			set(c, mi)
+		default:
+			set(c, (mi+mj)/2)
+		}
+	}
+}
+
+// fourPointEst returns the least four-point estimate of d[i][j], or +Inf
+// if there is none.
+func (d DistanceMatrix) fourPointEst(i, j int) float64 {
+	di, dj := d[i], d[j]
+	x := math.Inf(1)
+	for k, dk := range d {
+		if k == i || k == j || math.IsNaN(di[k]) || math.IsNaN(dj[k]) {
+			continue
+		}
+		for l, dkl := range dk[:k] {
+			if l == i || l == j || math.IsNaN(dkl) ||
+				math.IsNaN(di[l]) || math.IsNaN(dj[l]) {
+				continue
+			}
+			e := math.Max(di[k]+dj[l], di[l]+dj[k]) - dkl
+			if e < x {
+				x = math.Max(e, 0) // (negative only for non-additive d)
+			}
+		}
+	}
+	return x
+}
+
+// threePointEst returns the least three-point estimate of d[i][j], or +Inf
+// if there is none.
+func (d DistanceMatrix) threePointEst(i, j int) float64 {
+	di, dj := d[i], d[j]
+	x := math.Inf(1)
+	for k := range d {
+		if k == i || k == j || math.IsNaN(di[k]) || math.IsNaN(dj[k]) {
+			continue
+		}
+		if e := math.Max(di[k], dj[k]); e < x {
+			x = e
+		}
+	}
+	return x
+}
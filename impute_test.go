@@ -0,0 +1,99 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleDistanceMatrix_Impute() {
+	nan := math.NaN()
+	d := cluster.DistanceMatrix{
+		{0, 13, 21, nan},
+		{13, 0, 12, 13},
+		{21, 12, 0, 13},
+		{nan, 13, 13, 0},
+	}
+	for _, method := range []int{cluster.ImputeMean, cluster.ImputePath,
+		cluster.ImputeAdditive} {
+		r, filled := d.Impute(method)
+		fmt.Println(filled, r[3][0])
+	}
+	// Output:
+	// [[3 0]] 15
+	// [[3 0]] 26
+	// [[3 0]] 22
+}
+
+func TestImpute(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	const n = 12
+	add, u, _ := cluster.RandomNoisyAdditiveMatrix(n, 0, rnd)
+	um, pl, _ := cluster.RandomUltrametricMatrix(n, rnd)
+	// distances between sister leaves are not determined by the others.
+	addSisters := func(i, j int) bool {
+		return u.LabeledAdjacencyList[i][0].To == u.LabeledAdjacencyList[j][0].To
+	}
+	umSisters := func(i, j int) bool {
+		return pl.Paths[i].From == pl.Paths[j].From
+	}
+	for _, tc := range []struct {
+		d       cluster.DistanceMatrix
+		method  int
+		sisters func(i, j int) bool
+	}{
+		{add, cluster.ImputeAdditive, addSisters},
+		{um, cluster.ImputeUltrametric, umSisters},
+	} {
+		d := tc.d.Clone()
+		var want [][2]int
+		for m := 0; m < 10; m++ {
+			i := 1 + rnd.Intn(n-1)
+			j := rnd.Intn(i)
+			if !math.IsNaN(d[i][j]) && !tc.sisters(i, j) {
+				d[i][j] = math.NaN()
+				d[j][i] = math.NaN()
+				want = append(want, [2]int{i, j})
+			}
+		}
+		r, filled := d.Impute(tc.method)
+		if len(filled) != len(want) {
+			t.Fatal(tc.method, "filled", filled, "want", want)
+		}
+		for _, c := range filled {
+			if x, y := r[c[0]][c[1]], tc.d[c[0]][c[1]]; math.Abs(x-y) > 1e-9 ||
+				r[c[1]][c[0]] != x {
+				t.Fatal(tc.method, c, "imputed", x, "want", y)
+			}
+		}
+		// mean and path fill all cells and leave d unmodified
+		for _, method := range []int{cluster.ImputeMean, cluster.ImputePath} {
+			r, filled := d.Impute(method)
+			if len(filled) != len(want) {
+				t.Fatal(method, "filled", filled, "want", want)
+			}
+			if err := r.Validate(); method == cluster.ImputePath && err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !math.IsNaN(d[want[0][0]][want[0][1]]) {
+			t.Fatal("receiver modified")
+		}
+	}
+	// diagonal, one-sided, and unreachable cells
+	nan := math.NaN()
+	d := cluster.DistanceMatrix{
+		{nan, 2, nan},
+		{nan, 0, nan},
+		{nan, nan, 0},
+	}
+	r, filled := d.Impute(cluster.ImputePath)
+	if fmt.Sprint(filled) != "[[0 0] [1 0]]" || !math.IsNaN(r[2][0]) {
+		t.Fatal(filled, r)
+	}
+}
@@ -2,7 +2,11 @@
 
 package cluster
 
-import "testing"
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
 
 func TestLimbWeight(t *testing.T) {
 	d := DistanceMatrix{
@@ -33,3 +37,43 @@ func TestLimbWeightSubMatrix(t *testing.T) {
 		t.Fatalf("got %f %d %d, want 10 0 1", min, i, k)
 	}
 }
+
+func TestSymEigen(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 1; n < 12; n++ {
+		a := make([][]float64, n)
+		for i := range a {
+			a[i] = make([]float64, n)
+			for j := 0; j <= i; j++ {
+				x := r.NormFloat64()
+				a[i][j] = x
+				a[j][i] = x
+			}
+		}
+		c := make([][]float64, n)
+		for i, ai := range a {
+			c[i] = append([]float64{}, ai...)
+		}
+		vals, vecs := symEigen(c)
+		for k, v := range vecs {
+			if k > 0 && vals[k] > vals[k-1] {
+				t.Fatal("eigenvalues not in decreasing order")
+			}
+			// a v = λ v, |v| = 1
+			norm := 0.
+			for i, ai := range a {
+				av := 0.
+				for j, aij := range ai {
+					av += aij * v[j]
+				}
+				if math.Abs(av-vals[k]*v[i]) > 1e-9 {
+					t.Fatal(n, "eigenvector", k, "component", i)
+				}
+				norm += v[i] * v[i]
+			}
+			if math.Abs(norm-1) > 1e-9 {
+				t.Fatal(n, "eigenvector", k, "norm", norm)
+			}
+		}
+	}
+}
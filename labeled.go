@@ -0,0 +1,327 @@
+// Public domain.
+
+package cluster
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/soniakeys/graph"
+)
+
+// LabeledDistanceMatrix is a DistanceMatrix with a name for each row and
+// column, such as a taxon or sample name.
+//
+// Methods of the embedded DistanceMatrix are available but methods defined
+// on LabeledDistanceMatrix keep names in step with rows.  Tree building
+// methods return trees with names for the leaves.  Tree builders keep
+// leaf numbers equal to matrix indexes even where they shuffle or destroy
+// the matrix, so Names[n] is always the name of leaf n.
+type LabeledDistanceMatrix struct {
+	DistanceMatrix
+	Names []string
+}
+
+// Clone allocates and copies a LabeledDistanceMatrix.
+func (ld LabeledDistanceMatrix) Clone() LabeledDistanceMatrix {
+	return LabeledDistanceMatrix{
+		ld.DistanceMatrix.Clone(),
+		append([]string{}, ld.Names...),
+	}
+}
+
+// Sub allocates and returns the submatrix of ld with rows and columns x,
+// with the corresponding names.
+//
+// See DistanceMatrix.Sub.
+func (ld LabeledDistanceMatrix) Sub(x []int) LabeledDistanceMatrix {
+	return LabeledDistanceMatrix{ld.DistanceMatrix.Sub(x), subNames(ld.Names, x)}
+}
+
+// Reorder returns the submatrix of ld with rows in the order of names.
+//
+// It returns an error if a name is not found in ld.Names.
+func (ld LabeledDistanceMatrix) Reorder(names []string) (LabeledDistanceMatrix, error) {
+	x, err := nameIndexes(ld.Names, names)
+	if err != nil {
+		return LabeledDistanceMatrix{}, err
+	}
+	return ld.Sub(x), nil
+}
+
+// subNames returns names[x[i]] for each i.
+func subNames(names []string, x []int) []string {
+	s := make([]string, len(x))
+	for i, xi := range x {
+		s[i] = names[xi]
+	}
+	return s
+}
+
+// nameIndexes finds the index in names of each of find.
+func nameIndexes(names, find []string) ([]int, error) {
+	m := make(map[string]int, len(names))
+	for i, n := range names {
+		m[n] = i
+	}
+	x := make([]int, len(find))
+	for i, n := range find {
+		j, ok := m[n]
+		if !ok {
+			return nil, fmt.Errorf("name %q not found", n)
+		}
+		x[i] = j
+	}
+	return x, nil
+}
+
+// Ultrametric constructs a rooted ultrametric tree as a Dendrogram with
+// leaf names.
+//
+// See DistanceMatrix.Ultrametric.  The receiver is not modified.
+func (ld LabeledDistanceMatrix) Ultrametric(cdf int) *Dendrogram {
+	return ld.Clone().UltrametricD(cdf)
+}
+
+// UltrametricD is the same as Ultrametric but is destructive on the
+// distance matrix of the receiver.  Names are not modified.
+func (ld LabeledDistanceMatrix) UltrametricD(cdf int) *Dendrogram {
+	dg := NewDendrogram(ld.DistanceMatrix.UltrametricD(cdf))
+	dg.Names = ld.Names
+	return dg
+}
+
+// UltrametricChecked is the same as Ultrametric but checks its arguments,
+// returning an error rather than panicking.
+//
+// See DistanceMatrix.UltrametricChecked.  The receiver is not modified.
+func (ld LabeledDistanceMatrix) UltrametricChecked(cdf int) (*Dendrogram, error) {
+	pl, ul, err := ld.DistanceMatrix.UltrametricChecked(cdf)
+	if err != nil {
+		return nil, err
+	}
+	dg := NewDendrogram(pl, ul)
+	dg.Names = ld.Names
+	return dg, nil
+}
+
+// LabeledTree is an unrooted tree with leaf names.
+//
+// The tree is represented as an undirected graph with edge labels indexing
+// Weights, as returned by DistanceMatrix.NeighborJoin.  Leaves are nodes
+// 0:len(Names).
+type LabeledTree struct {
+	graph.LabeledUndirected
+	Weights []float64
+	Names   []string
+}
+
+// NeighborJoin constructs an unrooted tree with leaf names using the
+// neighbor joining algorithm.
+//
+// See DistanceMatrix.NeighborJoin.  The receiver is not modified.
+func (ld LabeledDistanceMatrix) NeighborJoin() LabeledTree {
+	u, wt := ld.DistanceMatrix.NeighborJoin()
+	return LabeledTree{u, wt, ld.Names}
+}
+
+// NeighborJoinD is the same as NeighborJoin but is destructive on the
+// distance matrix of the receiver.  Names are not modified.
+func (ld LabeledDistanceMatrix) NeighborJoinD() LabeledTree {
+	u, wt := ld.DistanceMatrix.NeighborJoinD()
+	return LabeledTree{u, wt, ld.Names}
+}
+
+// NeighborJoinChecked is the same as NeighborJoin but checks the receiver,
+// returning an error rather than panicking.
+//
+// See DistanceMatrix.NeighborJoinChecked.  The receiver is not modified.
+func (ld LabeledDistanceMatrix) NeighborJoinChecked() (LabeledTree, error) {
+	u, wt, err := ld.DistanceMatrix.NeighborJoinChecked()
+	if err != nil {
+		return LabeledTree{}, err
+	}
+	return LabeledTree{u, wt, ld.Names}, nil
+}
+
+// NeighborJoinFast is the same as NeighborJoin but uses
+// DistanceMatrix.NeighborJoinFast.
+func (ld LabeledDistanceMatrix) NeighborJoinFast() LabeledTree {
+	u, wt := ld.DistanceMatrix.NeighborJoinFast()
+	return LabeledTree{u, wt, ld.Names}
+}
+
+// RapidNJ is the same as NeighborJoin but uses DistanceMatrix.RapidNJ.
+func (ld LabeledDistanceMatrix) RapidNJ() LabeledTree {
+	u, wt := ld.DistanceMatrix.RapidNJ()
+	return LabeledTree{u, wt, ld.Names}
+}
+
+// BIONJ is the same as NeighborJoin but uses DistanceMatrix.BIONJ.
+func (ld LabeledDistanceMatrix) BIONJ() LabeledTree {
+	u, wt := ld.DistanceMatrix.BIONJ()
+	return LabeledTree{u, wt, ld.Names}
+}
+
+// UNJ is the same as NeighborJoin but uses DistanceMatrix.UNJ.
+func (ld LabeledDistanceMatrix) UNJ() LabeledTree {
+	u, wt := ld.DistanceMatrix.UNJ()
+	return LabeledTree{u, wt, ld.Names}
+}
+
+// LeastSquaresTree constructs an unrooted tree with leaf names minimizing
+// a least squares criterion.
+//
+// See DistanceMatrix.LeastSquaresTree.
+func (ld LabeledDistanceMatrix) LeastSquaresTree(power float64) (t LabeledTree, rss float64) {
+	u, wt, rss := ld.DistanceMatrix.LeastSquaresTree(power)
+	return LabeledTree{u, wt, ld.Names}, rss
+}
+
+// MinimumEvolution constructs an unrooted tree with leaf names by balanced
+// minimum evolution.
+//
+// See DistanceMatrix.MinimumEvolution.
+func (ld LabeledDistanceMatrix) MinimumEvolution() LabeledTree {
+	u, wt := ld.DistanceMatrix.MinimumEvolution()
+	return LabeledTree{u, wt, ld.Names}
+}
+
+// AdditiveTree constructs an unrooted tree with leaf names from an
+// additive distance matrix.
+//
+// See DistanceMatrix.AdditiveTree.
+func (ld LabeledDistanceMatrix) AdditiveTree() LabeledTree {
+	u, wt := ld.DistanceMatrix.AdditiveTree()
+	return LabeledTree{u, wt, ld.Names}
+}
+
+// AdditiveTreeChecked is the same as AdditiveTree but checks that the
+// matrix is additive, returning an error rather than building a
+// meaningless tree.
+//
+// See DistanceMatrix.AdditiveTreeChecked.
+func (ld LabeledDistanceMatrix) AdditiveTreeChecked() (LabeledTree, error) {
+	u, wt, err := ld.DistanceMatrix.AdditiveTreeChecked()
+	if err != nil {
+		return LabeledTree{}, err
+	}
+	return LabeledTree{u, wt, ld.Names}, nil
+}
+
+// WriteNewick writes the tree in Newick format with leaf names.
+//
+// See WriteNewickUnrooted.
+func (t LabeledTree) WriteNewick(w io.Writer) error {
+	return WriteNewickUnrooted(w, t.LabeledUndirected, t.Weights, t.Names)
+}
+
+// RootMidpoint roots the tree at the midpoint of the longest path between
+// two leaves, returning a Dendrogram with leaf names.
+//
+// See the function RootMidpoint.
+func (t LabeledTree) RootMidpoint() *Dendrogram {
+	dg := NewDendrogram(RootMidpoint(t.LabeledUndirected, t.Weights, len(t.Names)))
+	dg.Names = t.Names
+	return dg
+}
+
+// RootOutgroup roots the tree on the edge separating the named outgroup
+// leaves from the other leaves, returning a Dendrogram with leaf names.
+//
+// See the function RootOutgroup.
+func (t LabeledTree) RootOutgroup(outgroup []string) (*Dendrogram, error) {
+	x, err := nameIndexes(t.Names, outgroup)
+	if err != nil {
+		return nil, err
+	}
+	og := make([]graph.NI, len(x))
+	for i, l := range x {
+		og[i] = graph.NI(l)
+	}
+	pl, ul, err := RootOutgroup(t.LabeledUndirected, t.Weights, len(t.Names), og)
+	if err != nil {
+		return nil, err
+	}
+	dg := NewDendrogram(pl, ul)
+	dg.Names = t.Names
+	return dg, nil
+}
+
+// ReadNewickTree reads a single tree in Newick format as a LabeledTree.
+//
+// See ReadNewick.
+func ReadNewickTree(r io.Reader) (LabeledTree, error) {
+	u, wt, names, err := ReadNewick(r)
+	return LabeledTree{u, wt, names}, err
+}
+
+// PatristicDist returns the matrix of path lengths between the leaves of t,
+// labeled with the leaf names.
+//
+// See NewPatristicDist.
+func (t LabeledTree) PatristicDist() LabeledDistanceMatrix {
+	return LabeledDistanceMatrix{
+		NewPatristicDist(t.LabeledUndirected, t.Weights, len(t.Names)),
+		t.Names,
+	}
+}
+
+// WritePhylip writes the matrix in PHYLIP format with its names.
+//
+// See WritePhylipDist.
+func (ld LabeledDistanceMatrix) WritePhylip(w io.Writer, lower bool, mode int) error {
+	return WritePhylipDist(w, ld.DistanceMatrix, ld.Names, lower, mode)
+}
+
+// ReadPhylip reads a distance matrix in PHYLIP format as a
+// LabeledDistanceMatrix.
+//
+// See ReadPhylipDist.
+func ReadPhylip(r io.Reader, mode int) (LabeledDistanceMatrix, error) {
+	d, names, err := ReadPhylipDist(r, mode)
+	return LabeledDistanceMatrix{d, names}, err
+}
+
+// LabeledSimilarityMatrix is a SimilarityMatrix with a name for each row
+// and column.
+type LabeledSimilarityMatrix struct {
+	SimilarityMatrix
+	Names []string
+}
+
+// Clone allocates and copies a LabeledSimilarityMatrix.
+func (ls LabeledSimilarityMatrix) Clone() LabeledSimilarityMatrix {
+	return LabeledSimilarityMatrix{
+		ls.SimilarityMatrix.Clone(),
+		append([]string{}, ls.Names...),
+	}
+}
+
+// Sub allocates and returns the submatrix of ls with rows and columns x,
+// with the corresponding names.
+func (ls LabeledSimilarityMatrix) Sub(x []int) LabeledSimilarityMatrix {
+	return LabeledSimilarityMatrix{ls.SimilarityMatrix.Sub(x), subNames(ls.Names, x)}
+}
+
+// Reorder returns the submatrix of ls with rows in the order of names.
+//
+// It returns an error if a name is not found in ls.Names.
+func (ls LabeledSimilarityMatrix) Reorder(names []string) (LabeledSimilarityMatrix, error) {
+	x, err := nameIndexes(ls.Names, names)
+	if err != nil {
+		return LabeledSimilarityMatrix{}, err
+	}
+	return ls.Sub(x), nil
+}
+
+// CAST implements "Cluster Affinity Search Technique", returning clusters
+// as lists of names.
+//
+// See SimilarityMatrix.CAST.
+func (ls LabeledSimilarityMatrix) CAST(v float64) (clusters [][]string) {
+	for _, c := range ls.SimilarityMatrix.CAST(v) {
+		clusters = append(clusters, subNames(ls.Names, c))
+	}
+	return
+}
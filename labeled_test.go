@@ -0,0 +1,97 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleLabeledDistanceMatrix() {
+	ld := cluster.LabeledDistanceMatrix{
+		DistanceMatrix: cluster.DistanceMatrix{
+			{0, 23, 27, 20},
+			{23, 0, 30, 28},
+			{27, 30, 0, 30},
+			{20, 28, 30, 0},
+		},
+		Names: []string{"w", "x", "y", "z"},
+	}
+	ld.NeighborJoin().WriteNewick(os.Stdout)
+	r, err := ld.Reorder([]string{"z", "w", "x"})
+	fmt.Println(err)
+	fmt.Println(r.Names)
+	fmt.Println(r.DistanceMatrix)
+	// Output:
+	// (z:12,w:8,(x:13.5,y:16.5):2);
+	// <nil>
+	// [z w x]
+	// [0 20 28]
+	// [20 0 23]
+	// [28 23 0]
+}
+
+func ExampleLabeledDistanceMatrix_Ultrametric() {
+	ld := cluster.LabeledDistanceMatrix{
+		DistanceMatrix: cluster.DistanceMatrix{
+			{0, 4, 8, 8},
+			{4, 0, 8, 8},
+			{8, 8, 0, 6},
+			{8, 8, 6, 0},
+		},
+		Names: []string{"a", "b", "c", "d"},
+	}
+	dg := ld.Ultrametric(cluster.DAVG)
+	dg.WriteNewick(os.Stdout, nil)
+	fmt.Println(dg.ClusterNames(dg.Cut(2)))
+	// Output:
+	// ((a:2,b:2):2,(c:3,d:3):1);
+	// [[a b] [c d]]
+}
+
+func ExampleLabeledDistanceMatrix_NeighborJoinChecked() {
+	ld := cluster.LabeledDistanceMatrix{
+		DistanceMatrix: cluster.DistanceMatrix{
+			{0, 23, 27, 20},
+			{23, 0, 30, 28},
+			{27, 30, 0, 30},
+			{20, 28, 30, 0},
+		},
+		Names: []string{"w", "x", "y", "z"},
+	}
+	t, err := ld.NeighborJoinChecked()
+	fmt.Println(err)
+	t.WriteNewick(os.Stdout)
+	ld.DistanceMatrix[1][2] = 29
+	_, err = ld.NeighborJoinChecked()
+	fmt.Println(err)
+	_, err = ld.UltrametricChecked(cluster.DAVG)
+	fmt.Println(err)
+	_, err = ld.AdditiveTreeChecked()
+	fmt.Println(err)
+	// Output:
+	// <nil>
+	// (z:12,w:8,(x:13.5,y:16.5):2);
+	// not symmetric: d[2][1] != d[1][2]
+	// not symmetric: d[2][1] != d[1][2]
+	// not symmetric: d[2][1] != d[1][2]
+}
+
+func ExampleLabeledSimilarityMatrix_CAST() {
+	ls := cluster.LabeledSimilarityMatrix{
+		SimilarityMatrix: cluster.NewPearsonSim([]cluster.Point{
+			{1, 2, 3}, {3, 2, 1}, {2, 4, 6.5}, {6, 4, 2}}),
+		Names: []string{"up1", "down1", "up2", "down2"},
+	}
+	clusters := ls.CAST(1.5)
+	for _, c := range clusters {
+		sort.Strings(c)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i][0] < clusters[j][0] })
+	fmt.Println(clusters)
+	// Output:
+	// [[down1 down2] [up1 up2]]
+}
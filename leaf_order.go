@@ -0,0 +1,150 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math"
+
+	"github.com/soniakeys/graph"
+)
+
+// OptimalLeafOrder finds a leaf order for dg that minimizes the sum of
+// distances in dm between adjacent leaves.
+//
+// The method is that of Bar-Joseph, Gifford, and Jaakkola, "Fast optimal
+// leaf ordering for hierarchical clustering," 2001.  Only orders possible
+// by flipping the children of internal nodes are considered, so the
+// clusters of dg are unchanged.
+//
+// DistanceMatrix dm must be the matrix dg was constructed from, or at least
+// have rows and columns corresponding to the leaves of dg.  It is not
+// modified.  The Dendrogram dg must be binary, as are those constructed from
+// Ultrametric results.  OptimalLeafOrder panics if a node has other than
+// zero or two children.
+//
+// Returned is a new Dendrogram with reordered child lists and the new leaf
+// order.  The new Dendrogram shares the parent list and labels of dg.
+//
+// Time complexity is O(n^3) in the number of leaves.
+func (dg *Dendrogram) OptimalLeafOrder(dm DistanceMatrix) (*Dendrogram, []graph.NI) {
+	if dg.Root < 0 {
+		return dg, dg.order
+	}
+	// m[i][j] is the minimum cost of an ordering of the subtree rooted at
+	// LCA(i, j) with leftmost leaf i and rightmost leaf j.
+	nn := len(dg.Paths)
+	m := make([][]float64, nn)
+	for _, l := range dg.order {
+		m[l] = make([]float64, nn)
+	}
+	// c[k] is workspace holding, for a fixed i, the minimum over y of
+	// m[i][y] + dm[y][k], where y can end an ordering starting with i.
+	c := make([]float64, nn)
+	var f func(graph.NI)
+	f = func(v graph.NI) {
+		ch := dg.ch[v]
+		switch len(ch) {
+		case 0:
+			return
+		case 2:
+		default:
+			panic("OptimalLeafOrder: dendrogram not binary")
+		}
+		w, x := ch[0], ch[1]
+		f(w)
+		f(x)
+		for _, s := range [2][2]graph.NI{{w, x}, {x, w}} {
+			w, x := s[0], s[1]
+			for _, i := range dg.Leaves(w) {
+				outW := dg.outer(w, i)
+				for _, k := range dg.Leaves(x) {
+					min := math.Inf(1)
+					for _, y := range outW {
+						if t := m[i][y] + dm[y][k]; t < min {
+							min = t
+						}
+					}
+					c[k] = min
+				}
+				for _, j := range dg.Leaves(x) {
+					min := math.Inf(1)
+					for _, k := range dg.outer(x, j) {
+						if t := c[k] + m[k][j]; t < min {
+							min = t
+						}
+					}
+					m[i][j] = min
+				}
+			}
+		}
+	}
+	f(dg.Root)
+
+	// trace back from the best pair of ends
+	ch := make([][]graph.NI, nn)
+	var tb func(v, i, j graph.NI)
+	tb = func(v, i, j graph.NI) {
+		if len(dg.ch[v]) == 0 {
+			return
+		}
+		w, x := dg.ch[v][0], dg.ch[v][1]
+		if !dg.Ancestor(w, i) {
+			w, x = x, w
+		}
+		ch[v] = []graph.NI{w, x}
+		min := math.Inf(1)
+		var kMin, yMin graph.NI
+		for _, y := range dg.outer(w, i) {
+			for _, k := range dg.outer(x, j) {
+				if t := m[i][y] + dm[y][k] + m[k][j]; t < min {
+					min = t
+					yMin, kMin = y, k
+				}
+			}
+		}
+		tb(w, i, yMin)
+		tb(x, kMin, j)
+	}
+	min := math.Inf(1)
+	var iMin, jMin graph.NI
+	if len(dg.ch[dg.Root]) == 0 {
+		iMin, jMin = dg.Root, dg.Root
+	}
+	for _, i := range dg.Leaves(dg.Root) {
+		for _, j := range dg.Leaves(dg.Root) {
+			if i < j && dg.LCA(i, j) == dg.Root && m[i][j] < min {
+				min = m[i][j]
+				iMin, jMin = i, j
+			}
+		}
+	}
+	tb(dg.Root, iMin, jMin)
+	o := newDendrogram(dg.FromList, dg.Labels, ch)
+	o.Names = dg.Names
+	return o, o.order
+}
+
+// outer returns the leaves of subtree v that can be at one end of an
+// ordering of v with leaf i at the other end.
+//
+// For a leaf it is just i.  Otherwise it is the leaves under the child of v
+// not containing i.
+func (dg *Dendrogram) outer(v, i graph.NI) []graph.NI {
+	ch := dg.ch[v]
+	if len(ch) == 0 {
+		return dg.Leaves(v)
+	}
+	if dg.Ancestor(ch[0], i) {
+		return dg.Leaves(ch[1])
+	}
+	return dg.Leaves(ch[0])
+}
+
+// LeafOrderCost returns the sum of distances in dm between adjacent leaves
+// of the given order.
+func (dm DistanceMatrix) LeafOrderCost(order []graph.NI) (sum float64) {
+	for i := 1; i < len(order); i++ {
+		sum += dm[order[i-1]][order[i]]
+	}
+	return
+}
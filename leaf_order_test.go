@@ -0,0 +1,92 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+	"github.com/soniakeys/graph"
+)
+
+func ExampleDendrogram_OptimalLeafOrder() {
+	d := cluster.DistanceMatrix{
+		{0, 20, 17, 11},
+		{20, 0, 20, 13},
+		{17, 20, 0, 10},
+		{11, 13, 10, 0},
+	}
+	dg := d.Dendrogram(cluster.DAVG)
+	fmt.Println(dg.LeafOrder(), d.LeafOrderCost(dg.LeafOrder()))
+	_, order := dg.OptimalLeafOrder(d)
+	fmt.Println(order, d.LeafOrderCost(order))
+	// Output:
+	// [1 0 2 3] 47
+	// [0 2 3 1] 40
+}
+
+// TestOptimalLeafOrder compares with brute force over all permutations
+// that keep clusters contiguous.
+func TestOptimalLeafOrder(t *testing.T) {
+	for _, d := range []cluster.DistanceMatrix{
+		cluster.RandomAdditiveMatrix(6),
+		cluster.NewEuclideanDist([]cluster.Point{
+			{1, 2}, {7, 3}, {2, 2}, {9, 9}, {4, 1}, {8, 2}, {3, 6}}),
+	} {
+		dg := d.Dendrogram(cluster.DAVG)
+		o, order := dg.OptimalLeafOrder(d)
+		got := d.LeafOrderCost(order)
+		want := math.Inf(1)
+		perm := make([]graph.NI, len(d))
+		var f func(int, uint)
+		f = func(x int, used uint) {
+			if x == len(perm) {
+				if contiguous(dg, perm) {
+					if c := d.LeafOrderCost(perm); c < want {
+						want = c
+					}
+				}
+				return
+			}
+			for l := range perm {
+				if used&(1<<uint(l)) == 0 {
+					perm[x] = graph.NI(l)
+					f(x+1, used|1<<uint(l))
+				}
+			}
+		}
+		f(0, 0)
+		if math.Abs(got-want) > 1e-9 {
+			t.Fatalf("cost %g, want %g", got, want)
+		}
+		if !contiguous(dg, o.LeafOrder()) {
+			t.Fatal("clusters changed")
+		}
+	}
+}
+
+// contiguous tests that leaves of every node of dg are contiguous in order.
+func contiguous(dg *cluster.Dendrogram, order []graph.NI) bool {
+	pos := make([]int, len(order))
+	for i, l := range order {
+		pos[l] = i
+	}
+	for n := range dg.Paths {
+		lv := dg.Leaves(graph.NI(n))
+		min, max := len(order), -1
+		for _, l := range lv {
+			if pos[l] < min {
+				min = pos[l]
+			}
+			if pos[l] > max {
+				max = pos[l]
+			}
+		}
+		if max-min+1 != len(lv) {
+			return false
+		}
+	}
+	return true
+}
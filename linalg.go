@@ -0,0 +1,166 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math"
+	"sort"
+)
+
+// symEigen returns the eigenvalues and eigenvectors of symmetric matrix a
+// by the cyclic Jacobi method.
+//
+// Eigenvalues are returned in decreasing order.  Eigenvector k, of unit
+// length, is vecs[k].  Argument a is destroyed.
+func symEigen(a [][]float64) (vals []float64, vecs [][]float64) {
+	n := len(a)
+	v := make([][]float64, n) // columns are eigenvectors
+	for i := range v {
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+	scale := 0.
+	for i, ai := range a {
+		for _, aij := range ai[:i+1] {
+			scale += aij * aij
+		}
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		off := 0.
+		for i, ai := range a {
+			for _, aij := range ai[:i] {
+				off += aij * aij
+			}
+		}
+		if off <= 1e-30*scale {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := a[p][q]
+				if apq == 0 {
+					continue
+				}
+				θ := (a[q][q] - a[p][p]) / (2 * apq)
+				t := 1 / (math.Abs(θ) + math.Sqrt(θ*θ+1))
+				if θ < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return a[order[i]][order[i]] > a[order[j]][order[j]]
+	})
+	vals = make([]float64, n)
+	vecs = make([][]float64, n)
+	for k, c := range order {
+		vals[k] = a[c][c]
+		vk := make([]float64, n)
+		for i := range vk {
+			vk[i] = v[i][c]
+		}
+		vecs[k] = vk
+	}
+	return
+}
+
+// rightSingular returns the singular values and right singular vectors of
+// the n×m matrix x by the one-sided Jacobi method of Hestenes.
+//
+// Singular values are returned in decreasing order.  Right singular vector
+// k, of unit length m, is vecs[k].  Argument x is destroyed.
+func rightSingular(x [][]float64) (vals []float64, vecs [][]float64) {
+	m := 0
+	if len(x) > 0 {
+		m = len(x[0])
+	}
+	v := make([][]float64, m) // columns are right singular vectors
+	for i := range v {
+		v[i] = make([]float64, m)
+		v[i][i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		rotated := false
+		for p := 0; p < m; p++ {
+			for q := p + 1; q < m; q++ {
+				var α, β, γ float64
+				for _, xi := range x {
+					α += xi[p] * xi[p]
+					β += xi[q] * xi[q]
+					γ += xi[p] * xi[q]
+				}
+				if γ == 0 || math.Abs(γ) <= 1e-15*math.Sqrt(α*β) {
+					continue
+				}
+				rotated = true
+				ζ := (β - α) / (2 * γ)
+				t := 1 / (math.Abs(ζ) + math.Sqrt(ζ*ζ+1))
+				if ζ < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for _, xi := range x {
+					xp, xq := xi[p], xi[q]
+					xi[p] = c*xp - s*xq
+					xi[q] = s*xp + c*xq
+				}
+				for _, vi := range v {
+					vp, vq := vi[p], vi[q]
+					vi[p] = c*vp - s*vq
+					vi[q] = s*vp + c*vq
+				}
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+	norm := make([]float64, m)
+	for _, xi := range x {
+		for j, xij := range xi {
+			norm[j] += xij * xij
+		}
+	}
+	order := make([]int, m)
+	for i := range order {
+		order[i] = i
+		norm[i] = math.Sqrt(norm[i])
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return norm[order[i]] > norm[order[j]]
+	})
+	vals = make([]float64, m)
+	vecs = make([][]float64, m)
+	for k, c := range order {
+		vals[k] = norm[c]
+		vk := make([]float64, m)
+		for i := range vk {
+			vk[i] = v[i][c]
+		}
+		vecs[k] = vk
+	}
+	return
+}
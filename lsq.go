@@ -0,0 +1,220 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math"
+
+	"github.com/soniakeys/graph"
+)
+
+// OLS, FitchMargoliash constants for the power argument of least squares
+// functions.
+const (
+	OLS             = 0 // ordinary least squares
+	FitchMargoliash = 2 // weights 1/d^2
+)
+
+// LeastSquares fits branch lengths of a tree topology to dm by weighted
+// least squares.
+//
+// The tree is given as an undirected graph with edge labels, as returned by
+// NeighborJoin, AdditiveTree, or ReadNewick.  Leaves must be nodes
+// 0:len(dm).  Returned is a weight list indexed by the edge labels and the
+// residual sum of squares.
+//
+// Branch lengths minimize the sum over leaf pairs i < j of
+// w[i][j] * (d[i][j] - p[i][j])^2 where p[i][j] is the path length between
+// i and j in the tree and w[i][j] = d[i][j]^-power.  Power OLS, 0, gives
+// ordinary least squares.  Power FitchMargoliash, 2, gives the weighting of
+// Fitch and Margoliash.  Pairs with zero distance are given weight 1.  The
+// returned rss is the minimized, weighted sum.
+//
+// Branch lengths are not constrained to be non-negative.  Where lengths are
+// not determined by the distances, as for the two edges at a node of degree
+// two, undetermined lengths are 0.
+//
+// Time complexity is O(m^3) in the number of edges m, plus the time to sum
+// path lengths, O(n^2 m) in the number of leaves n.  The receiver is not
+// modified.
+func (dm DistanceMatrix) LeastSquares(u graph.LabeledUndirected, power float64) (wt []float64, rss float64) {
+	a := u.LabeledAdjacencyList
+	m := 0
+	for _, hs := range a {
+		for _, h := range hs {
+			if int(h.Label) >= m {
+				m = int(h.Label) + 1
+			}
+		}
+	}
+	// normal equations ata * wt = atd
+	ata := make([][]float64, m)
+	for i := range ata {
+		ata[i] = make([]float64, m)
+	}
+	atd := make([]float64, m)
+	leafPaths(a, len(dm), func(i, j int, path []graph.LI) {
+		w := lsWeight(dm[i][j], power)
+		for _, e := range path {
+			atd[e] += w * dm[i][j]
+			ae := ata[e]
+			for _, f := range path {
+				ae[f] += w
+			}
+		}
+	})
+	wt = solveSym(ata, atd)
+	leafPaths(a, len(dm), func(i, j int, path []graph.LI) {
+		p := 0.
+		for _, e := range path {
+			p += wt[e]
+		}
+		r := dm[i][j] - p
+		rss += lsWeight(dm[i][j], power) * r * r
+	})
+	return
+}
+
+func lsWeight(d, power float64) float64 {
+	if power == 0 || d == 0 {
+		return 1
+	}
+	return math.Pow(d, -power)
+}
+
+// leafPaths calls f with the edge labels of the path between each pair of
+// leaves i > j.
+func leafPaths(a graph.LabeledAdjacencyList, nLeaves int, f func(i, j int, path []graph.LI)) {
+	var path []graph.LI
+	var df func(i int, n, p graph.NI)
+	df = func(i int, n, p graph.NI) {
+		if int(n) < i {
+			f(i, int(n), path)
+		}
+		for _, h := range a[n] {
+			if h.To != p {
+				path = append(path, h.Label)
+				df(i, h.To, n)
+				path = path[:len(path)-1]
+			}
+		}
+	}
+	for i := 1; i < nLeaves; i++ {
+		df(i, graph.NI(i), -1)
+	}
+}
+
+// solveSym solves a * x = b for symmetric positive semidefinite a by
+// Gauss-Jordan elimination with diagonal pivots.  Variables with no pivot,
+// those not determined by the system, are set to 0.  Arguments are
+// destroyed.
+func solveSym(a [][]float64, b []float64) []float64 {
+	n := len(b)
+	scale := 0.
+	for i, ai := range a {
+		scale = math.Max(scale, math.Abs(ai[i]))
+	}
+	tol := 1e-12 * scale
+	x := make([]float64, n)
+	for c, ac := range a {
+		p := ac[c]
+		if !(p > tol) {
+			continue
+		}
+		for k, ak := range a {
+			if k == c || ak[c] == 0 {
+				continue
+			}
+			f := ak[c] / p
+			for j := c; j < n; j++ {
+				ak[j] -= f * ac[j]
+			}
+			b[k] -= f * b[c]
+		}
+	}
+	for c, ac := range a {
+		if ac[c] > tol {
+			x[c] = b[c] / ac[c]
+		}
+	}
+	return x
+}
+
+// LeastSquaresTree searches for a tree minimizing the least squares
+// criterion of LeastSquares.
+//
+// The search starts from the NeighborJoin tree and makes nearest neighbor
+// interchanges around internal edges as long as one reduces the residual
+// sum of squares.  The result is a local optimum.  Returned are the tree,
+// in the representation of NeighborJoin, the fitted branch lengths, and the
+// residual sum of squares.
+//
+// Each step refits all branch lengths, so the search is practical for up
+// to perhaps a hundred leaves.  The receiver is not modified.
+func (dm DistanceMatrix) LeastSquaresTree(power float64) (u graph.LabeledUndirected, wt []float64, rss float64) {
+	u, _ = dm.NeighborJoin()
+	wt, rss = dm.LeastSquares(u, power)
+	a := u.LabeledAdjacencyList
+	n := graph.NI(len(dm))
+	for improved := true; improved; {
+		improved = false
+		for x := n; int(x) < len(a) && !improved; x++ {
+			for _, hy := range a[x] {
+				y := hy.To
+				if y < x || y < n || improved {
+					continue
+				}
+				// x has neighbors y, b, ...; y has x, c, ...
+				for _, hb := range a[x] {
+					b := hb.To
+					if b == y || improved {
+						continue
+					}
+					for _, hc := range a[y] {
+						c := hc.To
+						if c == x {
+							continue
+						}
+						nni(a, x, y, b, c)
+						w, r := dm.LeastSquares(u, power)
+						if r < rss*(1-1e-12) {
+							wt, rss = w, r
+							improved = true
+							break
+						}
+						nni(a, x, y, c, b) // undo
+					}
+					break // one neighbor b of x covers both interchanges
+				}
+			}
+		}
+	}
+	return
+}
+
+// nni exchanges neighbor b of x with neighbor c of y, where x and y are
+// adjacent.  Edge labels stay with the positions, so b-y takes the label
+// of c-y and c-x takes the label of b-x.
+func nni(a graph.LabeledAdjacencyList, x, y, b, c graph.NI) {
+	repl := func(n, from, to graph.NI) graph.LI {
+		for i, h := range a[n] {
+			if h.To == from {
+				a[n][i].To = to
+				return h.Label
+			}
+		}
+		panic("nni: not adjacent")
+	}
+	lb := repl(x, b, c)
+	lc := repl(y, c, b)
+	for i, h := range a[b] {
+		if h.To == x {
+			a[b][i] = graph.Half{To: y, Label: lc}
+		}
+	}
+	for i, h := range a[c] {
+		if h.To == y {
+			a[c][i] = graph.Half{To: x, Label: lb}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleDistanceMatrix_LeastSquares() {
+	d := cluster.DistanceMatrix{
+		{0, 3, 4, 3},
+		{3, 0, 4, 5},
+		{4, 4, 0, 2},
+		{3, 5, 2, 0},
+	}
+	u, _ := d.NeighborJoin()
+	for _, power := range []float64{cluster.OLS, cluster.FitchMargoliash} {
+		wt, rss := d.LeastSquares(u, power)
+		fmt.Printf("%.3f %.4f\n", wt, rss)
+	}
+	_, wt, rss := d.LeastSquaresTree(cluster.OLS)
+	fmt.Printf("%.3f %.4f\n", wt, rss)
+	// Output:
+	// [1.000 1.500 1.000 1.000 2.000] 1.0000
+	// [0.879 1.379 1.121 1.015 1.985] 0.0606
+	// [1.000 1.500 1.000 1.000 2.000] 1.0000
+}
+
+func TestLeastSquares(t *testing.T) {
+	for _, n := range []int{3, 5, 20} {
+		d := cluster.RandomAdditiveMatrix(n)
+		u, wt0 := d.AdditiveTree()
+		for _, power := range []float64{cluster.OLS, cluster.FitchMargoliash} {
+			wt, rss := d.LeastSquares(u, power)
+			if rss > 1e-9 {
+				t.Fatal("n =", n, "power", power, "rss", rss)
+			}
+			for l, w := range wt0 {
+				if math.Abs(w-wt[l]) > 1e-6 {
+					t.Fatal("n =", n, "power", power, "edge", l, w, wt[l])
+				}
+			}
+		}
+	}
+	// a perturbed additive matrix: the search should do no worse than NJ
+	d := cluster.RandomAdditiveMatrix(12)
+	for i := range d {
+		for j := range d[:i] {
+			x := d[i][j] * (1 + .2*math.Sin(float64(i*j)))
+			d[i][j], d[j][i] = x, x
+		}
+	}
+	u, _ := d.NeighborJoin()
+	_, rNJ := d.LeastSquares(u, cluster.OLS)
+	u, _, rss := d.LeastSquaresTree(cluster.OLS)
+	if rss > rNJ {
+		t.Fatal("search rss", rss, "> NJ rss", rNJ)
+	}
+	if _, r := d.LeastSquares(u, cluster.OLS); math.Abs(r-rss) > 1e-9*rss {
+		t.Fatal("returned rss", rss, "refit", r)
+	}
+}
@@ -0,0 +1,153 @@
+// Public domain.
+
+package cluster
+
+import "math"
+
+// ClassicalMDS embeds the points of a distance matrix in dim dimensions by
+// classical, or Torgerson, multidimensional scaling.
+//
+// The doubly centered matrix B = -1/2 J D² J, where D² holds the squared
+// distances and J is the centering matrix, is decomposed into eigenvalues
+// and eigenvectors.  Coordinate k of each point is taken from eigenvector k
+// scaled by the square root of eigenvalue k, for the dim largest
+// eigenvalues.  For a Euclidean distance matrix the result reproduces the
+// distances, up to rotation and reflection, when dim is at least the
+// dimension of the original points.  Coordinates for dimensions with
+// negative eigenvalues, which arise for non-Euclidean matrices, are 0.
+//
+// Returned are the points, which may be passed to KMeans or KMPP, all n
+// eigenvalues of B in decreasing order, and the fraction of variance
+// explained, the sum of the dim largest eigenvalues divided by the sum of
+// all positive eigenvalues.  Time complexity is O(n^3).  The receiver is
+// not modified.
+func (d DistanceMatrix) ClassicalMDS(dim int) (pts []Point, eig []float64, explained float64) {
+	n := len(d)
+	b := make([][]float64, n)
+	rowMean := make([]float64, n)
+	mean := 0.
+	for i, di := range d {
+		bi := make([]float64, n)
+		for j, dij := range di {
+			bi[j] = dij * dij
+			rowMean[i] += bi[j]
+		}
+		mean += rowMean[i]
+		rowMean[i] /= float64(n)
+		b[i] = bi
+	}
+	mean /= float64(n * n)
+	for i, bi := range b {
+		for j := range bi {
+			bi[j] = -.5 * (bi[j] - rowMean[i] - rowMean[j] + mean)
+		}
+	}
+	eig, vecs := symEigen(b)
+	pts = make([]Point, n)
+	for i := range pts {
+		pts[i] = make(Point, dim)
+	}
+	pos, kept := 0., 0.
+	for k, λ := range eig {
+		if λ <= 0 {
+			continue
+		}
+		pos += λ
+		if k >= dim {
+			continue
+		}
+		kept += λ
+		s := math.Sqrt(λ)
+		for i, p := range pts {
+			p[k] = s * vecs[k][i]
+		}
+	}
+	if pos > 0 {
+		explained = kept / pos
+	}
+	return
+}
+
+// SMACOF embeds the points of a distance matrix in dim dimensions by
+// stress majorization.
+//
+// The result minimizes raw stress, the sum over pairs i < j of
+// (d[i][j] - e[i][j])² where e[i][j] is the Euclidean distance between the
+// embedded points, by the SMACOF algorithm of de Leeuw.  Unlike
+// ClassicalMDS, it fits the distances directly and so is suited to
+// non-Euclidean matrices.
+//
+// Iteration starts from init if it is non-nil, otherwise from the result of
+// ClassicalMDS, and continues until stress decreases by a relative amount
+// less than 1e-9 or for maxIter iterations.  A non-nil init must hold a
+// point for each row of d, each with at least dim coordinates, of which the
+// first dim are used.  The function panics if init has fewer points or
+// points with fewer coordinates.  Returned are the points, which
+// may be passed to KMeans or KMPP, and the normalized stress, raw stress
+// divided by the sum of squared distances.  Each iteration is O(n^2 dim).
+// The receiver is not modified.
+func (d DistanceMatrix) SMACOF(dim int, init []Point, maxIter int) (pts []Point, stress float64) {
+	n := len(d)
+	if init != nil {
+		pts = make([]Point, n)
+		for i := range pts {
+			pts[i] = append(Point{}, init[i][:dim]...)
+		}
+	} else {
+		pts, _, _ = d.ClassicalMDS(dim)
+	}
+	ss := 0. // sum of squared distances, for normalization
+	for i, di := range d {
+		for _, dij := range di[:i] {
+			ss += dij * dij
+		}
+	}
+	if ss == 0 {
+		return
+	}
+	e := make([][]float64, n) // embedded distances
+	for i := range e {
+		e[i] = make([]float64, n)
+	}
+	rawStress := func() float64 {
+		s := 0.
+		for i, pi := range pts {
+			for j, pj := range pts[:i] {
+				x := math.Sqrt(pi.Sqd(pj))
+				e[i][j] = x
+				e[j][i] = x
+				r := d[i][j] - x
+				s += r * r
+			}
+		}
+		return s
+	}
+	s := rawStress()
+	next := make([]Point, n)
+	for i := range next {
+		next[i] = make(Point, dim)
+	}
+	for it := 0; it < maxIter; it++ {
+		// Guttman transform, next = B(X) X / n
+		for i, ni := range next {
+			ni.Clear()
+			for j, pj := range pts {
+				if j == i || e[i][j] == 0 {
+					continue
+				}
+				w := d[i][j] / e[i][j]
+				for k := range ni {
+					ni[k] += w * (pts[i][k] - pj[k])
+				}
+			}
+			ni.Mul(1 / float64(n))
+		}
+		pts, next = next, pts
+		last := s
+		s = rawStress()
+		if last-s < 1e-9*last {
+			break
+		}
+	}
+	return pts, s / ss
+}
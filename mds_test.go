@@ -0,0 +1,119 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleDistanceMatrix_ClassicalMDS() {
+	// corners of a 3x4 rectangle
+	d := cluster.NewEuclideanDist([]cluster.Point{
+		{0, 0}, {4, 0}, {4, 3}, {0, 3},
+	})
+	pts, eig, explained := d.ClassicalMDS(2)
+	for i, p := range pts[1:] {
+		for j := range pts[:i+1] {
+			fmt.Printf("%6.3f", math.Sqrt(p.Sqd(pts[j])))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%.3f %.3f\n", eig[:2], explained)
+	// one dimension explains the longer side
+	_, _, explained = d.ClassicalMDS(1)
+	fmt.Printf("%.3f\n", explained)
+	// Output:
+	//  4.000
+	//  5.000 3.000
+	//  3.000 5.000 4.000
+	// [16.000 9.000] 1.000
+	// 0.640
+}
+
+func ExampleDistanceMatrix_SMACOF() {
+	// three clusters in four dimensions
+	rnd := rand.New(rand.NewSource(1))
+	var orig []cluster.Point
+	for c := 0; c < 3; c++ {
+		for i := 0; i < 5; i++ {
+			p := make(cluster.Point, 4)
+			for k := range p {
+				p[k] = 10*float64((c+k)%3) + rnd.NormFloat64()
+			}
+			orig = append(orig, p)
+		}
+	}
+	d := cluster.NewPearsonDist(orig)
+	pts, stress := d.SMACOF(2, nil, 1000)
+	fmt.Printf("stress < .01: %t\n", stress < .01)
+	_, cNums, _, _ := cluster.KMPP(pts, 3)
+	fmt.Println(cNums[0] == cNums[4], cNums[5] == cNums[9], cNums[10] == cNums[14])
+	fmt.Println(cNums[0] != cNums[5], cNums[5] != cNums[10], cNums[0] != cNums[10])
+	// Output:
+	// stress < .01: true
+	// true true true
+	// true true true
+}
+
+func TestMDS(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	pts := make([]cluster.Point, 10)
+	for i := range pts {
+		pts[i] = cluster.Point{rnd.Float64(), rnd.Float64(), rnd.Float64()}
+	}
+	d := cluster.NewEuclideanDist(pts)
+	m, eig, explained := d.ClassicalMDS(3)
+	if math.Abs(explained-1) > 1e-9 || math.Abs(eig[3]) > 1e-9 {
+		t.Fatal(eig, explained)
+	}
+	if bs := maxDiff(d, cluster.NewEuclideanDist(m)); bs > 1e-9 {
+		t.Fatal("classical MDS distances differ by", bs)
+	}
+	// SMACOF from a poor start recovers Euclidean distances
+	init := make([]cluster.Point, len(pts))
+	for i := range init {
+		init[i] = cluster.Point{rnd.Float64(), rnd.Float64(), rnd.Float64()}
+	}
+	s, stress := d.SMACOF(3, init, 10000)
+	if stress > 1e-6 {
+		t.Fatal("stress", stress, maxDiff(d, cluster.NewEuclideanDist(s)))
+	}
+}
+
+func maxDiff(a, b cluster.DistanceMatrix) float64 {
+	m := 0.
+	for i, ai := range a {
+		for j, aij := range ai {
+			m = math.Max(m, math.Abs(aij-b[i][j]))
+		}
+	}
+	return m
+}
+
+func TestSMACOFInit(t *testing.T) {
+	d := cluster.NewEuclideanDist([]cluster.Point{{0, 0}, {4, 0}, {4, 3}})
+	for _, init := range [][]cluster.Point{
+		{{0, 0}, {1, 0}}, // too few points
+		{{0}, {1}, {1}},  // too few coordinates
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("no panic for init", init)
+				}
+			}()
+			d.SMACOF(2, init, 10)
+		}()
+	}
+	pts, _ := d.SMACOF(2, []cluster.Point{{0, 0, 9}, {1, 0, 9}, {1, 1, 9}}, 10)
+	for _, p := range pts {
+		if len(p) != 2 {
+			t.Fatal(pts)
+		}
+	}
+}
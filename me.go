@@ -0,0 +1,297 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math"
+
+	"github.com/soniakeys/graph"
+)
+
+// BalancedTreeLength returns the balanced minimum evolution length of a
+// tree topology for distance matrix dm.
+//
+// The tree is given as an undirected graph as returned by NeighborJoin.
+// Leaves must be nodes 0:len(dm) and the tree must be binary, with internal
+// nodes of degree three.  Edge labels and weights are not used.
+//
+// The length is computed by the formula of Pauplin, the sum over leaf pairs
+// i < j of d[i][j] * 2^(1-t) where t is the number of edges on the path
+// between i and j.  It equals the sum of branch lengths returned by
+// BalancedBranchLengths.
+func (dm DistanceMatrix) BalancedTreeLength(u graph.LabeledUndirected) float64 {
+	a := u.LabeledAdjacencyList
+	n := len(dm)
+	sum := 0.
+	var f func(i int, v, p graph.NI, t int)
+	f = func(i int, v, p graph.NI, t int) {
+		if int(v) < i {
+			sum += math.Ldexp(dm[i][v], 1-t)
+		}
+		for _, h := range a[v] {
+			if h.To != p {
+				f(i, h.To, v, t+1)
+			}
+		}
+	}
+	for i := 1; i < n; i++ {
+		f(i, graph.NI(i), -1, 0)
+	}
+	return sum
+}
+
+// BalancedBranchLengths returns branch lengths of a tree topology under the
+// balanced minimum evolution model.
+//
+// The tree is given as for BalancedTreeLength, and the result is a weight
+// list indexed by the edge labels of u.  Lengths are those of Desper and
+// Gascuel, "Fast and accurate phylogeny reconstruction algorithms based on
+// the minimum-evolution principle," 2002, computed from balanced average
+// distances between subtrees.  Lengths are not constrained to be
+// non-negative.
+//
+// BalancedBranchLengths panics if an internal node has degree other than
+// three.
+func (dm DistanceMatrix) BalancedBranchLengths(u graph.LabeledUndirected) []float64 {
+	a := u.LabeledAdjacencyList
+	m := 0
+	for _, hs := range a {
+		for _, h := range hs {
+			if int(h.Label) >= m {
+				m = int(h.Label) + 1
+			}
+		}
+	}
+	wt := make([]float64, m)
+	if len(dm) == 2 {
+		if m > 0 {
+			wt[0] = dm[0][1]
+		}
+		return wt
+	}
+	b := &balanced{dm: dm, a: a, memo: map[[4]graph.NI]float64{}}
+	nLeaves := graph.NI(len(dm))
+	for x, hs := range a {
+		x := graph.NI(x)
+		for _, h := range hs {
+			y := h.To
+			if y < x {
+				continue
+			}
+			if x < nLeaves {
+				// pendant edge x-y, where y has other neighbors p and q.
+				p, q := b.others(y, x)
+				wt[h.Label] = .5 * (b.avg(y, x, y, p) + b.avg(y, x, y, q) -
+					b.avg(y, p, y, q))
+				continue
+			}
+			// internal edge x-y with subtrees A, B beyond x; C, D beyond y.
+			pa, pb := b.others(x, y)
+			pc, pd := b.others(y, x)
+			wt[h.Label] = .25*(b.avg(x, pa, y, pc)+b.avg(x, pb, y, pd)+
+				b.avg(x, pa, y, pd)+b.avg(x, pb, y, pc)) -
+				.5*(b.avg(x, pa, x, pb)+b.avg(y, pc, y, pd))
+		}
+	}
+	return wt
+}
+
+// balanced computes balanced average distances between subtrees.  The
+// subtree (p, v) is that rooted at v, away from neighbor p.
+type balanced struct {
+	dm   DistanceMatrix
+	a    graph.LabeledAdjacencyList
+	memo map[[4]graph.NI]float64
+}
+
+// others returns the two neighbors of v other than n.
+func (b *balanced) others(v, n graph.NI) (p, q graph.NI) {
+	hs := b.a[v]
+	if len(hs) != 3 {
+		panic("BalancedBranchLengths: tree not binary")
+	}
+	o := make([]graph.NI, 0, 3)
+	for _, h := range hs {
+		if h.To != n {
+			o = append(o, h.To)
+		}
+	}
+	return o[0], o[1]
+}
+
+// avg returns the balanced average distance between subtrees (p1, v1) and
+// (p2, v2), which must be disjoint.
+func (b *balanced) avg(p1, v1, p2, v2 graph.NI) float64 {
+	k := [4]graph.NI{p1, v1, p2, v2}
+	if x, ok := b.memo[k]; ok {
+		return x
+	}
+	nLeaves := graph.NI(len(b.dm))
+	var x float64
+	switch {
+	case v1 >= nLeaves:
+		c1, c2 := b.others(v1, p1)
+		x = .5 * (b.avg(v1, c1, p2, v2) + b.avg(v1, c2, p2, v2))
+	case v2 >= nLeaves:
+		c1, c2 := b.others(v2, p2)
+		x = .5 * (b.avg(p1, v1, v2, c1) + b.avg(p1, v1, v2, c2))
+	default:
+		x = b.dm[v1][v2]
+	}
+	b.memo[k] = x
+	return x
+}
+
+// MinimumEvolution constructs an unrooted tree by balanced minimum
+// evolution.
+//
+// The search starts from the NeighborJoin tree and makes nearest neighbor
+// interchanges, then subtree prune and regraft moves, as long as one reduces
+// the balanced tree length of BalancedTreeLength.  The result is a local
+// optimum.  It is returned in the representation of NeighborJoin, with
+// branch lengths from BalancedBranchLengths.
+//
+// Each move is evaluated by computing the tree length in O(n^2) time for
+// n leaves.  A round of interchanges is O(n^3) and a round of prune and
+// regraft moves is O(n^4), so the search is practical for up to perhaps a
+// few hundred leaves.  The receiver is not modified.
+func (dm DistanceMatrix) MinimumEvolution() (u graph.LabeledUndirected, wt []float64) {
+	u, wt = dm.NeighborJoin()
+	if len(dm) < 4 {
+		return
+	}
+	a := u.LabeledAdjacencyList
+	best := dm.BalancedTreeLength(u)
+	better := func() bool {
+		if l := dm.BalancedTreeLength(u); l < best-1e-12*math.Abs(best) {
+			best = l
+			return true
+		}
+		return false
+	}
+	for dm.meNNI(a, better) || dm.meSPR(a, better) {
+	}
+	return u, dm.BalancedBranchLengths(u)
+}
+
+// meNNI tries nearest neighbor interchanges, keeping the first for which
+// better returns true.
+func (dm DistanceMatrix) meNNI(a graph.LabeledAdjacencyList, better func() bool) bool {
+	n := graph.NI(len(dm))
+	for x := n; int(x) < len(a); x++ {
+		for _, hy := range a[x] {
+			y := hy.To
+			if y < x {
+				continue
+			}
+			b := a[x][0].To
+			if b == y {
+				b = a[x][1].To
+			}
+			for _, hc := range a[y] {
+				c := hc.To
+				if c == x {
+					continue
+				}
+				nni(a, x, y, b, c)
+				if better() {
+					return true
+				}
+				nni(a, x, y, c, b)
+			}
+		}
+	}
+	return false
+}
+
+// meSPR tries subtree prune and regraft moves, keeping the first for which
+// better returns true.
+func (dm DistanceMatrix) meSPR(a graph.LabeledAdjacencyList, better func() bool) bool {
+	save := make(graph.LabeledAdjacencyList, len(a))
+	for p := graph.NI(len(dm)); int(p) < len(a); p++ {
+		for _, hs := range a[p] {
+			s := hs.To
+			// subtree (p, s) is pruned, p is regrafted into edges not
+			// in the subtree and not adjacent to p.
+			in := subtreeNodes(a, p, s)
+			var targets [][2]graph.NI
+			for x, xs := range a {
+				x := graph.NI(x)
+				if in[x] || x == p {
+					continue
+				}
+				for _, h := range xs {
+					if h.To > x && !in[h.To] && h.To != p {
+						targets = append(targets, [2]graph.NI{x, h.To})
+					}
+				}
+			}
+			for _, t := range targets {
+				for i, hs := range a {
+					save[i] = append(save[i][:0], hs...)
+				}
+				spr(a, p, s, t[0], t[1])
+				if better() {
+					return true
+				}
+				for i, hs := range save {
+					a[i] = append(a[i][:0], hs...)
+				}
+			}
+		}
+	}
+	return false
+}
+
+// subtreeNodes marks the nodes of subtree (p, s).
+func subtreeNodes(a graph.LabeledAdjacencyList, p, s graph.NI) []bool {
+	in := make([]bool, len(a))
+	var f func(v, p graph.NI)
+	f = func(v, p graph.NI) {
+		in[v] = true
+		for _, h := range a[v] {
+			if h.To != p {
+				f(h.To, v)
+			}
+		}
+	}
+	f(s, p)
+	return in
+}
+
+// spr prunes the subtree (p, s) with node p, joining the other two
+// neighbors of p, and regrafts it by inserting p into edge x-y.
+func spr(a graph.LabeledAdjacencyList, p, s, x, y graph.NI) {
+	var o []graph.Half // other neighbors of p
+	for _, h := range a[p] {
+		if h.To != s {
+			o = append(o, h)
+		}
+	}
+	n1, n2 := o[0].To, o[1].To
+	l1, l2 := o[0].Label, o[1].Label
+	// join n1-n2 with label l1, freeing l2
+	setHalf(a, n1, p, graph.Half{To: n2, Label: l1})
+	setHalf(a, n2, p, graph.Half{To: n1, Label: l1})
+	// insert p into x-y: x-p keeps the label of x-y, p-y takes l2
+	var lxy graph.LI
+	for _, h := range a[x] {
+		if h.To == y {
+			lxy = h.Label
+		}
+	}
+	setHalf(a, x, y, graph.Half{To: p, Label: lxy})
+	setHalf(a, y, x, graph.Half{To: p, Label: l2})
+	setHalf(a, p, n1, graph.Half{To: x, Label: lxy})
+	setHalf(a, p, n2, graph.Half{To: y, Label: l2})
+}
+
+// setHalf replaces the half edge from n to `to` with h.
+func setHalf(a graph.LabeledAdjacencyList, n, to graph.NI, h graph.Half) {
+	for i, hn := range a[n] {
+		if hn.To == to {
+			a[n][i] = h
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleDistanceMatrix_MinimumEvolution() {
+	d := cluster.DistanceMatrix{
+		{0, 12, 7, 8, 6, 7},
+		{12, 0, 7, 8, 8, 7},
+		{7, 7, 0, 7, 11, 5},
+		{8, 8, 7, 0, 5, 10},
+		{6, 8, 11, 5, 0, 11},
+		{7, 7, 5, 10, 11, 0},
+	}
+	u, _ := d.NeighborJoin()
+	fmt.Println("NJ:", d.BalancedTreeLength(u))
+	u, wt := d.MinimumEvolution()
+	fmt.Println("ME:", d.BalancedTreeLength(u))
+	cluster.WriteNewickUnrooted(os.Stdout, u, wt, nil)
+	// Output:
+	// NJ: 21.1875
+	// ME: 21.125
+	// ((((5:2.875,2:2.125):0.875,1:3.625):2.125,3:2.625):0.875,0:3.75,4:2.25);
+}
+
+func TestMinimumEvolution(t *testing.T) {
+	for _, n := range []int{3, 5, 20} {
+		d := cluster.RandomAdditiveMatrix(n)
+		u0, wt0 := d.AdditiveTree()
+		wt := d.BalancedBranchLengths(u0)
+		for l, w := range wt0 {
+			if math.Abs(w-wt[l]) > 1e-9 {
+				t.Fatal("n =", n, "edge", l, w, wt[l])
+			}
+		}
+		u, wt := d.MinimumEvolution()
+		if err := sameTree(n, u0, wt0, u, wt); err != nil {
+			t.Fatal("n =", n, err)
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	for k := 0; k < 10; k++ {
+		d := randomPointDist(r, 12, 3)
+		u, _ := d.NeighborJoin()
+		lNJ := d.BalancedTreeLength(u)
+		u, wt := d.MinimumEvolution()
+		l := d.BalancedTreeLength(u)
+		if l > lNJ {
+			t.Fatal("ME length", l, "> NJ length", lNJ)
+		}
+		s := 0.
+		for _, w := range wt {
+			s += w
+		}
+		if math.Abs(s-l) > 1e-9*l {
+			t.Fatal("branch length sum", s, "tree length", l)
+		}
+	}
+}
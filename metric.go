@@ -0,0 +1,208 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math"
+)
+
+// MatrixChange summarizes the difference between a distance matrix and a
+// repaired matrix over elements i < j.
+type MatrixChange struct {
+	L1      float64 // sum of absolute differences
+	L2      float64 // square root of the sum of squared differences
+	Max     float64 // largest absolute difference
+	Changed int     // number of elements changed
+}
+
+// matrixChange returns the change from d to r.
+func matrixChange(d, r DistanceMatrix) (c MatrixChange) {
+	for i, di := range d {
+		for j, dij := range di[:i] {
+			x := math.Abs(r[i][j] - dij)
+			if x == 0 {
+				continue
+			}
+			c.Changed++
+			c.L1 += x
+			c.L2 += x * x
+			c.Max = math.Max(c.Max, x)
+		}
+	}
+	c.L2 = math.Sqrt(c.L2)
+	return
+}
+
+// MetricClosure repairs violations of the triangle inequality by replacing
+// each distance with the length of the shortest path between the points.
+//
+// The result is the largest matrix not greater than d, element by element,
+// that satisfies the triangle inequality.  Only distances of violating
+// pairs are reduced; others are unchanged.  It is computed by the
+// Floyd-Warshall algorithm in O(n^3) time, repeated in the rare case that
+// floating point rounding leaves a violation.
+//
+// Argument d must pass the conditions of Validate other than the triangle
+// inequality.  The receiver is not modified.  Returned are the repaired
+// matrix and the change from d.
+func (d DistanceMatrix) MetricClosure() (DistanceMatrix, MatrixChange) {
+	r := d.Clone()
+	r.closure()
+	return r, matrixChange(d, r)
+}
+
+// closure applies shortest path closure to d in place.
+func (d DistanceMatrix) closure() {
+	for changed := true; changed; {
+		changed = false
+		for k, dk := range d {
+			for i, di := range d {
+				dik := di[k]
+				for j := 0; j < i; j++ {
+					if x := dik + dk[j]; x < di[j] {
+						di[j] = x
+						d[j][i] = x
+						changed = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// NormL1, NormL2 constants for the norm argument of MetricNearness.
+const (
+	NormL1 = 1
+	NormL2 = 2
+)
+
+// MetricNearness repairs violations of the triangle inequality by finding
+// a nearby matrix that satisfies it.
+//
+// With norm NormL2 the result is the matrix satisfying the triangle
+// inequality that minimizes the sum of squared differences from d over
+// elements i < j.  It is computed by the triangle fixing algorithm of
+// Brickell et al., "The metric nearness problem," 2008, a Dykstra
+// projection cycling over all triangles.  Iteration stops when a cycle
+// changes no element by more than a small tolerance relative to the largest
+// element of d.  A final MetricClosure removes violations remaining within
+// the tolerance, so that the result passes TriangleInequality.
+//
+// With norm NormL1 the sum of absolute differences is minimized
+// approximately by iteratively reweighted least squares, repeating the L2
+// computation with weights of 1/|r - d| for the current result r.
+//
+// Each cycle takes O(n^3) time.  Memory is used for each triangle
+// inequality active at the solution, up to O(n^3).  The method is practical
+// for up to perhaps a few hundred points.
+//
+// Argument d must pass the conditions of Validate other than the triangle
+// inequality.  The receiver is not modified.  Returned are the repaired
+// matrix and the change from d.
+func (d DistanceMatrix) MetricNearness(norm int) (DistanceMatrix, MatrixChange) {
+	scale := 0.
+	for _, di := range d {
+		for _, dij := range di {
+			scale = math.Max(scale, dij)
+		}
+	}
+	if scale == 0 { // all zero, already metric
+		return d.Clone(), MatrixChange{}
+	}
+	tol := 1e-10 * scale
+	var r DistanceMatrix
+	if norm == NormL1 {
+		var iw DistanceMatrix // inverse weights
+		const nIRLS = 20
+		for it := 0; it < nIRLS; it++ {
+			r = d.triangleFix(iw, tol)
+			iw = make(DistanceMatrix, len(d))
+			for i, di := range d {
+				iw[i] = make([]float64, i)
+				for j, dij := range di[:i] {
+					iw[i][j] = math.Max(math.Abs(r[i][j]-dij), 1e-6*scale)
+				}
+			}
+		}
+	} else {
+		r = d.triangleFix(nil, tol)
+	}
+	r.closure()
+	return r, matrixChange(d, r)
+}
+
+// triangleFix returns the nearest matrix to d satisfying the triangle
+// inequality in the norm weighted by 1/iw, where iw[i][j] for j < i is the
+// inverse weight of element i, j.  If iw is nil all weights are 1.
+func (d DistanceMatrix) triangleFix(iw DistanceMatrix, tol float64) DistanceMatrix {
+	r := d.Clone()
+	inv := func(i, j int) float64 {
+		if iw == nil {
+			return 1
+		}
+		return iw[i][j]
+	}
+	λ := map[int]float64{} // Dykstra corrections of active constraints
+	const maxCycles = 10000
+	for cycle := 0; cycle < maxCycles; cycle++ {
+		maxDelta := 0.
+		c := 0 // constraint number
+		for i, ri := range r {
+			for j, rj := range r[:i] {
+				for k := 0; k < j; k++ {
+					wij, wik, wjk := inv(i, j), inv(i, k), inv(j, k)
+					// constraints long side ij, ik, jk in turn, each as
+					// a.x <= 0 with a +1 for the long side, -1 for others.
+					for s := 0; s < 3; s++ {
+						var v float64
+						switch s {
+						case 0:
+							v = ri[j] - ri[k] - rj[k]
+						case 1:
+							v = ri[k] - ri[j] - rj[k]
+						default:
+							v = rj[k] - ri[j] - ri[k]
+						}
+						aWa := wij + wik + wjk
+						old := λ[c]
+						// undo the previous correction, project.
+						// (undo adds old*W^-1*a, which raises a.x by old*aWa)
+						nw := math.Max(0, (v+old*aWa)/aWa)
+						if nw != old {
+							t := old - nw // step along W^-1*a
+							var xij, xik, xjk float64
+							switch s {
+							case 0:
+								xij, xik, xjk = t*wij, -t*wik, -t*wjk
+							case 1:
+								xij, xik, xjk = -t*wij, t*wik, -t*wjk
+							default:
+								xij, xik, xjk = -t*wij, -t*wik, t*wjk
+							}
+							ri[j] += xij
+							ri[k] += xik
+							rj[k] += xjk
+							maxDelta = math.Max(maxDelta, math.Abs(t)*
+								math.Max(wij, math.Max(wik, wjk)))
+							if nw == 0 {
+								delete(λ, c)
+							} else {
+								λ[c] = nw
+							}
+						}
+						c++
+					}
+				}
+			}
+		}
+		if maxDelta <= tol {
+			break
+		}
+	}
+	for i, ri := range r {
+		for j, rij := range ri[:i] {
+			r[j][i] = rij
+		}
+	}
+	return r
+}
@@ -0,0 +1,108 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleDistanceMatrix_MetricClosure() {
+	d := cluster.DistanceMatrix{
+		{0, 1, 1, 3},
+		{1, 0, 4, 2},
+		{1, 4, 0, 2},
+		{3, 2, 2, 0},
+	}
+	r, c := d.MetricClosure()
+	fmt.Println(r)
+	fmt.Printf("%+v\n", c)
+	// Output:
+	// [0 1 1 3]
+	// [1 0 2 2]
+	// [1 2 0 2]
+	// [3 2 2 0]
+	// {L1:2 L2:2 Max:2 Changed:1}
+}
+
+func ExampleDistanceMatrix_MetricNearness() {
+	d := cluster.DistanceMatrix{
+		{0, 1, 1},
+		{1, 0, 4},
+		{1, 4, 0},
+	}
+	r, c := d.MetricNearness(cluster.NormL2)
+	for _, ri := range r {
+		fmt.Printf("%.4f\n", ri)
+	}
+	fmt.Printf("L1 %.4f, L2 %.4f\n", c.L1, c.L2)
+	_, c = d.MetricClosure()
+	fmt.Printf("L1 %.4f, L2 %.4f\n", c.L1, c.L2)
+	// Output:
+	// [0.0000 1.6667 1.6667]
+	// [1.6667 0.0000 3.3333]
+	// [1.6667 3.3333 0.0000]
+	// L1 2.0000, L2 1.1547
+	// L1 2.0000, L2 2.0000
+}
+
+func TestMetricNearness(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for n := 2; n < 12; n++ {
+		pts := make([]cluster.Point, n)
+		for i := range pts {
+			pts[i] = cluster.Point{rnd.Float64(), rnd.Float64(), rnd.Float64()}
+		}
+		// correlation distance, often violating the triangle inequality
+		d := cluster.NewPearsonDist(pts)
+		cc, ccC := d.MetricClosure()
+		l2, l2C := d.MetricNearness(cluster.NormL2)
+		l1, l1C := d.MetricNearness(cluster.NormL1)
+		for _, r := range []cluster.DistanceMatrix{cc, l2, l1} {
+			if err := r.Validate(); err != nil {
+				t.Fatal(n, err)
+			}
+		}
+		if ok, _, _, _ := d.TriangleInequality(); ok {
+			if ccC.Changed+l2C.Changed+l1C.Changed > 0 {
+				t.Fatal(n, "metric matrix changed")
+			}
+			continue
+		}
+		if l2C.L2 > ccC.L2*(1+1e-9) || l2C.L2 > l1C.L2*(1+1e-9) {
+			t.Fatal(n, "L2 change", l2C.L2, "closure", ccC.L2, "L1", l1C.L2)
+		}
+		if l1C.L1 > ccC.L1*(1+1e-6) || l1C.L1 > l2C.L1*(1+1e-6) {
+			t.Fatal(n, "L1 change", l1C.L1, "closure", ccC.L1, "L2", l2C.L1)
+		}
+	}
+	// KKT check for the L2 solution of a single violated triangle
+	d := cluster.DistanceMatrix{{0, 1, 1}, {1, 0, 4}, {1, 4, 0}}
+	r, _ := d.MetricNearness(cluster.NormL2)
+	if math.Abs(r[1][2]-10./3) > 1e-9 || math.Abs(r[0][1]-5./3) > 1e-9 {
+		t.Fatal(r)
+	}
+}
+
+func TestMetricNearnessFlat(t *testing.T) {
+	for _, x := range []float64{0, 2} {
+		d := cluster.DistanceMatrix{{0, x, x}, {x, 0, x}, {x, x, 0}}
+		for _, norm := range []int{cluster.NormL1, cluster.NormL2} {
+			r, c := d.MetricNearness(norm)
+			if c != (cluster.MatrixChange{}) {
+				t.Fatal(x, norm, c)
+			}
+			for i, ri := range r {
+				for j, rij := range ri {
+					if rij != d[i][j] {
+						t.Fatal(x, norm, r)
+					}
+				}
+			}
+		}
+	}
+}
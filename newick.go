@@ -0,0 +1,424 @@
+// Public domain.
+
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/soniakeys/graph"
+)
+
+// Newick format output.
+//
+// Leaves are labeled with names[n] where names is non-nil and n is in range.
+// Otherwise leaves are labeled with their node numbers.  Internal nodes are
+// not labeled.  Labels are quoted as needed according to the Newick rules,
+// with embedded single quotes doubled.
+
+// WriteNewick writes a rooted tree in Newick format.
+//
+// The tree is given as a parent list and labels as returned by
+// DistanceMatrix.Ultrametric.  Branch lengths are the Weight values.  The
+// root, which has no weight, is written without a branch length.  Children
+// are written in order of node number.
+//
+// See Dendrogram.WriteNewick to write children in dendrogram order.
+func WriteNewick(w io.Writer, pl graph.FromList, ul []Ultrametric, names []string) error {
+	return NewDendrogram(pl, ul).WriteNewick(w, names)
+}
+
+// WriteNewick writes the Dendrogram in Newick format, with children
+// written in the order of the Dendrogram child lists.
+//
+// If names is nil, dg.Names is used.  See the function WriteNewick.
+func (dg *Dendrogram) WriteNewick(w io.Writer, names []string) error {
+	if names == nil {
+		names = dg.Names
+	}
+	var b bytes.Buffer
+	var f func(graph.NI)
+	f = func(n graph.NI) {
+		if ch := dg.ch[n]; len(ch) > 0 {
+			b.WriteByte('(')
+			for i, c := range ch {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				f(c)
+			}
+			b.WriteByte(')')
+		} else {
+			b.WriteString(newickLeaf(n, names))
+		}
+		if dg.Paths[n].From >= 0 {
+			b.WriteByte(':')
+			b.WriteString(newickFloat(dg.Labels[n].Weight))
+		}
+	}
+	if dg.Root >= 0 {
+		f(dg.Root)
+	}
+	b.WriteString(";\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// WriteNewickUnrooted writes an unrooted tree in Newick format.
+//
+// The tree is given as an undirected graph and weight list as returned by
+// DistanceMatrix.NeighborJoin or DistanceMatrix.AdditiveTree.  Edge labels
+// index the weight list.  Nodes with a single neighbor are leaves.
+//
+// As Newick represents trees as rooted, output is arbitrarily rooted at the
+// neighbor of node 0, giving a multifurcation at the top level.  A tree of
+// two leaves is rooted at the midpoint of its single edge.
+func WriteNewickUnrooted(w io.Writer, u graph.LabeledUndirected, wt []float64, names []string) error {
+	a := u.LabeledAdjacencyList
+	var b bytes.Buffer
+	var f func(n, from graph.NI)
+	f = func(n, from graph.NI) {
+		ch := false
+		for _, h := range a[n] {
+			if h.To == from {
+				continue
+			}
+			if ch {
+				b.WriteByte(',')
+			} else {
+				b.WriteByte('(')
+				ch = true
+			}
+			f(h.To, n)
+			b.WriteByte(':')
+			b.WriteString(newickFloat(wt[h.Label]))
+		}
+		if ch {
+			b.WriteByte(')')
+		}
+		if len(a[n]) <= 1 {
+			b.WriteString(newickLeaf(n, names))
+		}
+	}
+	switch {
+	case len(a) == 0:
+	case len(a[0]) == 1 && len(a[a[0][0].To]) == 1:
+		// two leaves joined by a single edge, split at its midpoint
+		h := a[0][0]
+		half := newickFloat(wt[h.Label] / 2)
+		fmt.Fprintf(&b, "(%s:%s,%s:%s)", newickLeaf(0, names), half,
+			newickLeaf(h.To, names), half)
+	default:
+		start := graph.NI(0)
+		if len(a[0]) == 1 {
+			start = a[0][0].To
+		}
+		f(start, -1)
+	}
+	b.WriteString(";\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// newickLeaf returns the label for leaf n, quoted as needed.
+func newickLeaf(n graph.NI, names []string) string {
+	if int(n) < len(names) {
+		return NewickQuote(names[n])
+	}
+	return strconv.Itoa(int(n))
+}
+
+func newickFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// NewickQuote returns s quoted as needed for use as a Newick label.
+//
+// If s contains blanks, underscores, or any of the Newick punctuation
+// characters ()[]':;, it is enclosed in single quotes and embedded single
+// quotes are doubled.  Otherwise s is returned unchanged.  Note that an
+// unquoted underscore would be read back as a blank, so underscores are
+// quoted too.
+func NewickQuote(s string) string {
+	if !strings.ContainsAny(s, newickPunct+"_") {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// ParseError describes an error in reading formatted input.
+type ParseError struct {
+	Format string // "newick", "phylip", ...
+	Line   int    // line number, starting at 1
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s line %d: %s", e.Format, e.Line, e.Msg)
+}
+
+// ReadNewick reads a single tree in Newick format.
+//
+// The result is an unrooted tree in the representation returned by
+// DistanceMatrix.NeighborJoin and DistanceMatrix.AdditiveTree:  an
+// undirected graph with edge labels indexing a weight list.  Leaves are
+// nodes 0:len(names), numbered in order of appearance, and names holds
+// their labels.  Internal nodes follow.
+//
+// A root of degree two is removed, joining its two edges into one.  Missing
+// branch lengths are taken as 0.  Internal node labels and bracketed comments
+// are ignored.  In unquoted labels, underscores are read as blanks.
+//
+// Errors are returned as *ParseError.
+func ReadNewick(r io.Reader) (u graph.LabeledUndirected, wt []float64, names []string, err error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+	return ParseNewick(string(b))
+}
+
+// ParseNewick parses a single tree in Newick format.
+//
+// See ReadNewick.
+func ParseNewick(s string) (u graph.LabeledUndirected, wt []float64, names []string, err error) {
+	p := &newickParser{s: s, line: 1}
+	root, err := p.parse()
+	if err != nil {
+		return
+	}
+	// number leaves first, then internal nodes
+	nx := make([]graph.NI, len(p.nodes))
+	for i, n := range p.nodes {
+		if len(n.ch) == 0 {
+			nx[i] = graph.NI(len(names))
+			names = append(names, n.label)
+		}
+	}
+	dropRoot := len(p.nodes[root].ch) == 2
+	m := graph.NI(len(names))
+	for i, n := range p.nodes {
+		if len(n.ch) > 0 && !(i == root && dropRoot) {
+			nx[i] = m
+			m++
+		}
+	}
+	a := make(graph.LabeledAdjacencyList, m)
+	edge := func(n1, n2 graph.NI, w float64) {
+		l := graph.LI(len(wt))
+		wt = append(wt, w)
+		a[n1] = append(a[n1], graph.Half{To: n2, Label: l})
+		a[n2] = append(a[n2], graph.Half{To: n1, Label: l})
+	}
+	for i, n := range p.nodes {
+		if i == root && dropRoot {
+			c1, c2 := n.ch[0], n.ch[1]
+			edge(nx[c1], nx[c2], p.nodes[c1].len+p.nodes[c2].len)
+			continue
+		}
+		for _, c := range n.ch {
+			edge(nx[i], nx[c], p.nodes[c].len)
+		}
+	}
+	return graph.LabeledUndirected{LabeledAdjacencyList: a}, wt, names, nil
+}
+
+type newickNode struct {
+	ch    []int
+	label string
+	len   float64
+}
+
+type newickParser struct {
+	s     string
+	pos   int
+	line  int
+	nodes []newickNode
+}
+
+func (p *newickParser) errorf(format string, a ...interface{}) error {
+	return &ParseError{"newick", p.line, fmt.Sprintf(format, a...)}
+}
+
+// skip skips whitespace and comments, returns the next byte or 0 at end.
+func (p *newickParser) skip() (byte, error) {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; c {
+		case '\n':
+			p.line++
+			fallthrough
+		case ' ', '\t', '\r':
+			p.pos++
+		case '[':
+			start := p.line
+			for p.pos++; p.pos < len(p.s) && p.s[p.pos] != ']'; p.pos++ {
+				if p.s[p.pos] == '\n' {
+					p.line++
+				}
+			}
+			if p.pos == len(p.s) {
+				return 0, &ParseError{"newick", start, "unterminated comment"}
+			}
+			p.pos++
+		default:
+			return c, nil
+		}
+	}
+	return 0, nil
+}
+
+// parse parses a complete tree, returning the index of the root node.
+func (p *newickParser) parse() (int, error) {
+	root, err := p.subtree()
+	if err != nil {
+		return 0, err
+	}
+	c, err := p.skip()
+	if err != nil {
+		return 0, err
+	}
+	if c != ';' {
+		return 0, p.unexpected(c, "';'")
+	}
+	p.pos++
+	if c, err = p.skip(); err != nil {
+		return 0, err
+	}
+	if c != 0 {
+		return 0, p.errorf("unexpected %q after ';'", c)
+	}
+	return root, nil
+}
+
+func (p *newickParser) unexpected(c byte, want string) error {
+	if c == 0 {
+		return p.errorf("unexpected end of input, expected %s", want)
+	}
+	return p.errorf("unexpected %q, expected %s", c, want)
+}
+
+// subtree parses a subtree with optional label and branch length.
+func (p *newickParser) subtree() (int, error) {
+	x := len(p.nodes)
+	p.nodes = append(p.nodes, newickNode{})
+	c, err := p.skip()
+	if err != nil {
+		return 0, err
+	}
+	if c == '(' {
+		for {
+			p.pos++
+			ch, err := p.subtree()
+			if err != nil {
+				return 0, err
+			}
+			p.nodes[x].ch = append(p.nodes[x].ch, ch)
+			if c, err = p.skip(); err != nil {
+				return 0, err
+			}
+			if c == ')' {
+				p.pos++
+				break
+			}
+			if c != ',' {
+				return 0, p.unexpected(c, "',' or ')'")
+			}
+		}
+	}
+	label, err := p.label()
+	if err != nil {
+		return 0, err
+	}
+	p.nodes[x].label = label
+	if c, err = p.skip(); err != nil {
+		return 0, err
+	}
+	if c == ':' {
+		p.pos++
+		if _, err = p.skip(); err != nil {
+			return 0, err
+		}
+		start := p.pos
+		for p.pos < len(p.s) &&
+			!strings.ContainsRune(newickPunct, rune(p.s[p.pos])) {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return 0, p.errorf("invalid branch length %q", p.s[start:p.pos])
+		}
+		p.nodes[x].len = f
+	}
+	return x, nil
+}
+
+// newickPunct terminates unquoted labels and branch lengths.
+const newickPunct = " \t\r\n()[]':;,"
+
+// label parses an optional, possibly quoted, label.
+func (p *newickParser) label() (string, error) {
+	c, err := p.skip()
+	if err != nil {
+		return "", err
+	}
+	if c == '\'' {
+		start := p.line
+		var b strings.Builder
+		for p.pos++; ; p.pos++ {
+			if p.pos == len(p.s) {
+				return "", &ParseError{"newick", start, "unterminated quoted label"}
+			}
+			c := p.s[p.pos]
+			if c == '\'' {
+				if p.pos+1 < len(p.s) && p.s[p.pos+1] == '\'' {
+					p.pos++
+				} else {
+					p.pos++
+					return b.String(), nil
+				}
+			} else if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+		}
+	}
+	start := p.pos
+	for p.pos < len(p.s) &&
+		!strings.ContainsRune(newickPunct, rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return strings.Replace(p.s[start:p.pos], "_", " ", -1), nil
+}
+
+// NewPatristicDist constructs the n×n distance matrix of path lengths
+// between the leaves of a tree, where n is nLeaves.
+//
+// The tree is given as an undirected graph with edge labels indexing the
+// weight list wt, as returned by NeighborJoin, AdditiveTree, or ReadNewick.
+// Leaves must be nodes 0:nLeaves.
+//
+// For a tree with non-negative weights the result is additive.
+func NewPatristicDist(u graph.LabeledUndirected, wt []float64, nLeaves int) DistanceMatrix {
+	a := u.LabeledAdjacencyList
+	dist := make(DistanceMatrix, nLeaves)
+	pd := make([]float64, len(a)) // path distance from current leaf
+	var f func(n, from graph.NI)
+	f = func(n, from graph.NI) {
+		for _, h := range a[n] {
+			if h.To != from {
+				pd[h.To] = pd[n] + wt[h.Label]
+				f(h.To, n)
+			}
+		}
+	}
+	for i := range dist {
+		pd[i] = 0
+		f(graph.NI(i), -1)
+		dist[i] = append([]float64{}, pd[:nLeaves]...)
+	}
+	return dist
+}
@@ -0,0 +1,127 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleWriteNewick() {
+	d := cluster.DistanceMatrix{
+		{0, 4, 8, 8},
+		{4, 0, 8, 8},
+		{8, 8, 0, 6},
+		{8, 8, 6, 0},
+	}
+	pl, ul := d.Ultrametric(cluster.DAVG)
+	cluster.WriteNewick(os.Stdout, pl, ul, nil)
+	cluster.WriteNewick(os.Stdout, pl, ul, []string{"a", "b", "c", "d"})
+	// Output:
+	// ((0:2,1:2):2,(2:3,3:3):1);
+	// ((a:2,b:2):2,(c:3,d:3):1);
+}
+
+func ExampleWriteNewickUnrooted() {
+	d := cluster.DistanceMatrix{
+		{0, 13, 21, 22},
+		{13, 0, 12, 13},
+		{21, 12, 0, 13},
+		{22, 13, 13, 0},
+	}
+	u, wt := d.AdditiveTree()
+	names := []string{"Homo sapiens", "Pan", "Gorilla", "Pongo's"}
+	cluster.WriteNewickUnrooted(os.Stdout, u, wt, names)
+	// Output:
+	// (Pan:2,'Homo sapiens':11,(Gorilla:6,'Pongo''s':7):4);
+}
+
+func ExampleNewickQuote() {
+	fmt.Println(cluster.NewickQuote("Pan"))
+	fmt.Println(cluster.NewickQuote("Pan_paniscus"))
+	fmt.Println(cluster.NewickQuote("it's"))
+	// Output:
+	// Pan
+	// 'Pan_paniscus'
+	// 'it''s'
+}
+
+func ExampleReadNewick() {
+	u, wt, names, err := cluster.ReadNewick(strings.NewReader(
+		"((A:2,'B b':3):1,[comment]C_c:4,D:5);"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%q\n", names)
+	for n, to := range u.LabeledAdjacencyList {
+		for _, h := range to {
+			fmt.Printf("%d: to %d weight %g\n", n, h.To, wt[h.Label])
+		}
+	}
+	fmt.Println(cluster.NewPatristicDist(u, wt, len(names)))
+	_, _, _, err = cluster.ParseNewick("((A,B),\n(C,D);")
+	fmt.Println(err)
+	// Output:
+	// ["A" "B b" "C c" "D"]
+	// 0: to 5 weight 2
+	// 1: to 5 weight 3
+	// 2: to 4 weight 4
+	// 3: to 4 weight 5
+	// 4: to 5 weight 1
+	// 4: to 2 weight 4
+	// 4: to 3 weight 5
+	// 5: to 4 weight 1
+	// 5: to 0 weight 2
+	// 5: to 1 weight 3
+	// [0 5 7 8]
+	// [5 0 8 9]
+	// [7 8 0 9]
+	// [8 9 9 0]
+	// newick line 2: unexpected ';', expected ',' or ')'
+}
+
+// TestNewickRoundTrip writes and reads back a random additive tree.
+func TestNewickRoundTrip(t *testing.T) {
+	for _, d := range []cluster.DistanceMatrix{
+		cluster.RandomAdditiveMatrix(12),
+		{{0, 3}, {3, 0}},
+	} {
+		u, wt := d.AdditiveTree()
+		names := make([]string, len(d))
+		for i := range names {
+			names[i] = fmt.Sprintf("leaf %d's", i)
+		}
+		var b bytes.Buffer
+		if err := cluster.WriteNewickUnrooted(&b, u, wt, names); err != nil {
+			t.Fatal(err)
+		}
+		s := b.String()
+		u2, wt2, names2, err := cluster.ReadNewick(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names2) != len(names) {
+			t.Fatalf("%s read back as %d leaves", s, len(names2))
+		}
+		// names2 may be in a different order.  map back to d's order.
+		x := map[string]int{}
+		for i, n := range names2 {
+			x[n] = i
+		}
+		p := cluster.NewPatristicDist(u2, wt2, len(names2))
+		for i, ni := range names {
+			for j, nj := range names {
+				if p[x[ni]][x[nj]] != d[i][j] {
+					t.Fatalf("d[%d][%d] = %g, patristic %g",
+						i, j, d[i][j], p[x[ni]][x[nj]])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,424 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math"
+	"sort"
+
+	"github.com/soniakeys/graph"
+)
+
+// NeighborJoinFast constructs an unrooted tree from a distance matrix using
+// the neighbor joining algorithm.
+//
+// It produces the same tree as NeighborJoin, in the same representation,
+// but iterates rather than recursing and updates row sums incrementally
+// rather than recomputing them.  Joined rows are marked inactive rather than
+// deleted from the matrix.  Time complexity is O(n^3) in the number of
+// leaves.
+//
+// Rounding differs from NeighborJoin so where there are ties in the joining
+// criterion, the tie may be broken differently.  Ties always occur in the
+// last joins, among four and three remaining nodes, but any choice there
+// gives the same topology and branch lengths, differing only in numbering
+// of internal nodes.  Otherwise results differ only for input with ties.
+//
+// See also NeighborJoinFastD and RapidNJ.
+func (dm DistanceMatrix) NeighborJoinFast() (u graph.LabeledUndirected, wt []float64) {
+	return dm.Clone().NeighborJoinFastD()
+}
+
+// NeighborJoinFastD is the same as NeighborJoinFast but is destructive on
+// the receiver.
+func (dm DistanceMatrix) NeighborJoinFastD() (u graph.LabeledUndirected, wt []float64) {
+	return newNJ(dm).run(njScan)
+}
+
+// RapidNJ constructs an unrooted tree from a distance matrix using the
+// neighbor joining algorithm with the search heuristic of RapidNJ.
+//
+// The method is that of Simonsen, Mailund, and Pedersen, "Rapid
+// neighbour-joining," 2008.  Rows of the distance matrix are kept sorted
+// so that the search for the pair to join can stop scanning a row as soon
+// as a lower bound on the joining criterion exceeds the best value found.
+// In typical cases most of the matrix is not examined.  Worst case time
+// complexity is still O(n^3).
+//
+// Results are the same as NeighborJoinFast except possibly where there are
+// near ties in the joining criterion.  See NeighborJoinFast.
+//
+// See also RapidNJD.
+func (dm DistanceMatrix) RapidNJ() (u graph.LabeledUndirected, wt []float64) {
+	return dm.Clone().RapidNJD()
+}
+
+// RapidNJD is the same as RapidNJ but is destructive on the receiver.
+func (dm DistanceMatrix) RapidNJD() (u graph.LabeledUndirected, wt []float64) {
+	nj := newNJ(dm)
+	nj.sortRows()
+	return nj.run(njRapid)
+}
+
+// BIONJ constructs an unrooted tree from a distance matrix using the BIONJ
+// variant of neighbor joining.
+//
+// The method is that of Gascuel, "BIONJ: an improved version of the NJ
+// algorithm based on a simple model of sequence data," 1997.  Pairs are
+// selected and limb lengths computed as for neighbor joining, but distances
+// to a joined node are a weighted combination of distances to the two
+// joined nodes, with weights chosen to minimize the variance of the new
+// distances.  Variances are modeled as initially proportional to distances
+// and are reduced along with distances.
+//
+// The tree is returned in the same representation as NeighborJoin.
+// The receiver is not modified.  See also BIONJD.
+func (dm DistanceMatrix) BIONJ() (u graph.LabeledUndirected, wt []float64) {
+	return dm.Clone().BIONJD()
+}
+
+// BIONJD is the same as BIONJ but is destructive on the receiver.
+func (dm DistanceMatrix) BIONJD() (u graph.LabeledUndirected, wt []float64) {
+	n := newNJ(dm)
+	n.variant = njBIONJ
+	n.v = dm.Clone()
+	return n.run(njScan)
+}
+
+// UNJ constructs an unrooted tree from a distance matrix using unweighted
+// neighbor joining.
+//
+// The method is that of Gascuel, "Concerning the NJ algorithm and its
+// unweighted version," 1997.  Pairs are selected as for neighbor joining,
+// but each node of the reduced matrix is weighted by the number of leaves
+// it represents, so that each leaf contributes equally to limb lengths and
+// to distances to joined nodes.  With n[k] the number of leaves represented
+// by node k and N the total number of leaves, the limb length from i to a
+// new node joining i and j is d[i][j]/2 + Σ n[k]*(d[i][k]-d[j][k]) /
+// (2*(N-n[i]-n[j])), summed over other nodes k.  Distances to the new node
+// weight i and j in proportion to n[i] and n[j].
+//
+// The tree is returned in the same representation as NeighborJoin.
+// The receiver is not modified.  See also UNJD.
+func (dm DistanceMatrix) UNJ() (u graph.LabeledUndirected, wt []float64) {
+	return dm.Clone().UNJD()
+}
+
+// UNJD is the same as UNJ but is destructive on the receiver.
+func (dm DistanceMatrix) UNJD() (u graph.LabeledUndirected, wt []float64) {
+	n := newNJ(dm)
+	n.variant = njUNJ
+	n.size = make([]float64, len(dm))
+	for i := range n.size {
+		n.size[i] = 1
+	}
+	return n.run(njScan)
+}
+
+// nj holds working state for iterative neighbor joining.
+type nj struct {
+	dm      DistanceMatrix
+	act     []int          // active matrix indexes, in order
+	nx      []graph.NI     // node number corresponding to matrix index
+	td      []float64      // total-distance (row sum) of each active row
+	gen     []int          // generation of each matrix index, for rapid
+	rows    [][]njCell     // sorted rows, for rapid
+	variant int            // njNJ, njBIONJ, njUNJ
+	v       DistanceMatrix // variances, for BIONJ
+	size    []float64      // leaves represented by matrix index, for UNJ
+}
+
+// njCell is an element of a sorted row.
+type njCell struct {
+	d   float64 // distance
+	j   int     // column
+	gen int     // generation of column j when sorted
+}
+
+const (
+	njScan  = iota // full scan of lower triangle
+	njRapid        // sorted row search with bounds
+)
+
+const (
+	njNJ    = iota // neighbor joining
+	njBIONJ        // BIONJ
+	njUNJ          // unweighted neighbor joining
+)
+
+func newNJ(dm DistanceMatrix) *nj {
+	n := &nj{
+		dm:  dm,
+		act: make([]int, len(dm)),
+		nx:  make([]graph.NI, len(dm)),
+		td:  make([]float64, len(dm)),
+	}
+	for i, di := range dm {
+		n.act[i] = i
+		n.nx[i] = graph.NI(i)
+		t := 0.
+		for _, d := range di {
+			t += d
+		}
+		n.td[i] = t
+	}
+	return n
+}
+
+// njJoin records a join of nodes n1 and n2 with limb lengths l1 and l2.
+type njJoin struct {
+	n1, n2 graph.NI
+	l1, l2 float64
+}
+
+// run joins until two nodes remain, then builds the tree.
+//
+// The tree is built as NeighborJoin builds it, with the same scheme of
+// node numbers, edge labels, and order of half arcs.
+func (n *nj) run(search int) (u graph.LabeledUndirected, wt []float64) {
+	dm := n.dm
+	var joins []njJoin
+	for len(n.act) > 2 {
+		var p1, p2 int // positions in act, p1 < p2
+		if search == njRapid {
+			p1, p2 = n.rapidClosest()
+		} else {
+			p1, p2 = n.closest()
+		}
+		d1, d2 := n.act[p1], n.act[p2]
+		d21 := dm[d2][d1]
+		var Δ float64
+		if n.variant == njUNJ {
+			Δ = n.unjΔ(d1, d2)
+		} else {
+			Δ = (n.td[d2] - n.td[d1]) / float64(len(n.act)-2)
+		}
+		l1 := .5 * (d21 - Δ)
+		l2 := .5 * (d21 + Δ)
+		joins = append(joins, njJoin{
+			n1: n.nx[d1],
+			n2: n.nx[d2],
+			l1: l1,
+			l2: l2,
+		})
+		switch n.variant {
+		case njBIONJ:
+			n.reduce(d1, d2, l1, l2, n.bionjλ(d1, d2))
+		case njUNJ:
+			n.reduce(d1, d2, l1, l2, n.size[d1]/(n.size[d1]+n.size[d2]))
+		default:
+			n.reduce(d1, d2, .5*d21, .5*d21, .5)
+		}
+		copy(n.act[p2:], n.act[p2+1:])
+		n.act = n.act[:len(n.act)-1]
+		n.nx[d1] = graph.NI(len(dm) + len(joins) - 1)
+		if search == njRapid {
+			n.gen[d1]++
+			n.sortRow(d1)
+		}
+	}
+	return n.tree(joins)
+}
+
+// unjΔ computes the size-weighted mean of d[d2][k] - d[d1][k] over active
+// nodes k other than d1 and d2.
+func (n *nj) unjΔ(d1, d2 int) float64 {
+	s, w := 0., 0.
+	for _, k := range n.act {
+		if k != d1 && k != d2 {
+			s += n.size[k] * (n.dm[d2][k] - n.dm[d1][k])
+			w += n.size[k]
+		}
+	}
+	return s / w
+}
+
+// reduce replaces row and column d1 with distances to the node joining d1
+// and d2, where v1 and v2 are the limb lengths from the new node to d1
+// and d2, and λ is the weight given to d1.  Row sums are updated.
+// For BIONJ, variances are updated.  For UNJ, sizes are updated.
+func (n *nj) reduce(d1, d2 int, v1, v2, λ float64) {
+	dm := n.dm
+	di1 := dm[d1]
+	di2 := dm[d2]
+	if n.variant == njUNJ {
+		n.size[d1] += n.size[d2]
+	}
+	var vi1, vi2 []float64
+	var v12 float64
+	if n.variant == njBIONJ {
+		vi1, vi2 = n.v[d1], n.v[d2]
+		v12 = vi1[d2]
+	}
+	t1 := 0.
+	for _, k := range n.act {
+		if k == d1 || k == d2 {
+			continue
+		}
+		mn := λ*(di1[k]-v1) + (1-λ)*(di2[k]-v2)
+		n.td[k] += mn - di1[k] - di2[k]
+		t1 += mn
+		di1[k] = mn
+		dm[k][d1] = mn
+		if vi1 != nil {
+			vn := λ*vi1[k] + (1-λ)*vi2[k] - λ*(1-λ)*v12
+			vi1[k] = vn
+			n.v[k][d1] = vn
+		}
+	}
+	di1[d1] = 0
+	n.td[d1] = t1
+}
+
+// bionjλ computes the BIONJ weight of d1 in joining d1 and d2.
+func (n *nj) bionjλ(d1, d2 int) float64 {
+	v12 := n.v[d1][d2]
+	if !(v12 > 0) {
+		return .5
+	}
+	s := 0.
+	for _, k := range n.act {
+		if k != d1 && k != d2 {
+			s += n.v[d2][k] - n.v[d1][k]
+		}
+	}
+	λ := .5 + s/(2*float64(len(n.act)-2)*v12)
+	switch {
+	case λ < 0:
+		λ = 0
+	case λ > 1:
+		λ = 1
+	}
+	return λ
+}
+
+// closest finds the pair minimizing the neighbor joining criterion by
+// scanning the lower triangle of active rows, in the same order as
+// NeighborJoin.  It returns positions in n.act, smaller first.
+func (n *nj) closest() (p1, p2 int) {
+	dm := n.dm
+	r := float64(len(n.act) - 2)
+	min := math.Inf(1)
+	for pi, i := range n.act {
+		di := dm[i]
+		tdi := n.td[i]
+		for pj, j := range n.act[:pi] {
+			if q := r*di[j] - tdi - n.td[j]; q < min {
+				min = q
+				p1, p2 = pj, pi
+			}
+		}
+	}
+	return
+}
+
+// sortRows sorts all rows, initializing state for rapidClosest.
+func (n *nj) sortRows() {
+	n.gen = make([]int, len(n.dm))
+	n.rows = make([][]njCell, len(n.dm))
+	for i := range n.dm {
+		n.sortRow(i)
+	}
+}
+
+// sortRow sorts the active elements of row i, excluding the diagonal.
+func (n *nj) sortRow(i int) {
+	di := n.dm[i]
+	row := n.rows[i][:0]
+	for _, j := range n.act {
+		if j != i {
+			row = append(row, njCell{di[j], j, n.gen[j]})
+		}
+	}
+	sort.Slice(row, func(a, b int) bool { return row[a].d < row[b].d })
+	n.rows[i] = row
+}
+
+// rapidClosest finds the pair minimizing the neighbor joining criterion
+// using sorted rows.
+//
+// For row i and any j, the criterion is bounded below by
+// r*d[i][j] - td[i] - max(td), so the scan of a row stops when this bound
+// exceeds the minimum found so far.  Cells of a sorted row are stale when
+// their column has become inactive or has been reused for a joined node.
+// Stale cells are skipped and removed.  Pairs with a reused column are
+// found in the row of that column, which is sorted anew.
+//
+// It returns positions in n.act, smaller first.
+func (n *nj) rapidClosest() (p1, p2 int) {
+	r := float64(len(n.act) - 2)
+	active := make(map[int]int, len(n.act)) // matrix index -> position
+	maxTd := math.Inf(-1)
+	for p, i := range n.act {
+		active[i] = p
+		if n.td[i] > maxTd {
+			maxTd = n.td[i]
+		}
+	}
+	min := math.Inf(1)
+	for _, i := range n.act {
+		tdi := n.td[i]
+		row := n.rows[i]
+		live := row[:0]
+		x := 0
+		for ; x < len(row); x++ {
+			c := row[x]
+			if _, ok := active[c.j]; !ok || c.gen != n.gen[c.j] {
+				continue // drop stale cell
+			}
+			live = append(live, c)
+			if r*c.d-tdi-maxTd > min {
+				x++
+				break
+			}
+			// compute q as closest does, with the row of greater position
+			// first, and break ties by the order that closest scans.
+			pi, pj := active[i], active[c.j]
+			if pi < pj {
+				pi, pj = pj, pi
+			}
+			q := r*c.d - n.td[n.act[pi]] - n.td[n.act[pj]]
+			if q < min || q == min && (pi < p2 || pi == p2 && pj < p1) {
+				min = q
+				p1, p2 = pj, pi
+			}
+		}
+		n.rows[i] = append(live, row[x:]...)
+	}
+	return
+}
+
+// tree builds the tree from the join records and the two remaining
+// active nodes.
+func (n *nj) tree(joins []njJoin) (u graph.LabeledUndirected, wt []float64) {
+	dm := n.dm
+	nLeaves := len(dm)
+	m := nLeaves + len(joins)
+	if len(n.act) < 2 {
+		// degenerate, zero or one leaf
+		return graph.LabeledUndirected{
+			LabeledAdjacencyList: make(graph.LabeledAdjacencyList, m),
+		}, nil
+	}
+	a0, a1 := n.act[0], n.act[1]
+	wt = make([]float64, 1, m-1)
+	wt[0] = dm[a0][a1]
+	t := make(graph.LabeledAdjacencyList, m)
+	n0 := n.nx[a0]
+	n1 := n.nx[a1]
+	t[n0] = []graph.Half{{To: n1}}
+	t[n1] = []graph.Half{{To: n0}}
+	for x := len(joins) - 1; x >= 0; x-- {
+		j := joins[x]
+		v := graph.NI(nLeaves + x)
+		wx1 := graph.LI(len(wt))
+		wx2 := wx1 + 1
+		wt = append(wt, j.l1, j.l2)
+		t[v] = append(t[v],
+			graph.Half{To: j.n1, Label: wx1},
+			graph.Half{To: j.n2, Label: wx2})
+		t[j.n1] = append(t[j.n1], graph.Half{To: v, Label: wx1})
+		t[j.n2] = append(t[j.n2], graph.Half{To: v, Label: wx2})
+	}
+	return graph.LabeledUndirected{LabeledAdjacencyList: t}, wt
+}
@@ -0,0 +1,131 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+	"github.com/soniakeys/graph"
+)
+
+func ExampleDistanceMatrix_RapidNJ() {
+	d := cluster.DistanceMatrix{
+		{0, 23, 27, 20},
+		{23, 0, 30, 28},
+		{27, 30, 0, 30},
+		{20, 28, 30, 0},
+	}
+	tree, wt := d.RapidNJ()
+	fmt.Println("n1  n2  weight")
+	for n, to := range tree.LabeledAdjacencyList {
+		for _, h := range to {
+			fmt.Printf("%d  %2d   %6.3f\n", n, h.To, wt[h.Label])
+		}
+	}
+	// Output:
+	// n1  n2  weight
+	// 0   5    8.000
+	// 1   4   13.500
+	// 2   4   16.500
+	// 3   5   12.000
+	// 4   5    2.000
+	// 4   1   13.500
+	// 4   2   16.500
+	// 5   3   12.000
+	// 5   0    8.000
+	// 5   4    2.000
+}
+
+// randomPointDist returns a distance matrix for random points, which should
+// be free of ties.
+func randomPointDist(r *rand.Rand, n, dim int) cluster.DistanceMatrix {
+	pts := make([]cluster.Point, n)
+	for i := range pts {
+		p := make(cluster.Point, dim)
+		for j := range p {
+			p[j] = r.Float64()
+		}
+		pts[i] = p
+	}
+	return cluster.NewEuclideanDist(pts)
+}
+
+// sameTree tests that two trees over n leaves have the same topology and
+// branch lengths, to within a tolerance, by comparing path lengths between
+// leaves.
+func sameTree(n int, u1 graph.LabeledUndirected, wt1 []float64,
+	u2 graph.LabeledUndirected, wt2 []float64) error {
+	p1 := cluster.NewPatristicDist(u1, wt1, n)
+	p2 := cluster.NewPatristicDist(u2, wt2, n)
+	for i, p1i := range p1 {
+		for j, p1ij := range p1i {
+			if math.Abs(p1ij-p2[i][j]) > 1e-9 {
+				return fmt.Errorf("path length %d-%d: %g != %g",
+					i, j, p1ij, p2[i][j])
+			}
+		}
+	}
+	return nil
+}
+
+func TestNeighborJoinFast(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for _, n := range []int{3, 4, 10, 50, 120} {
+		d := randomPointDist(r, n, 5)
+		u0, wt0 := d.NeighborJoin()
+		u1, wt1 := d.NeighborJoinFast()
+		if err := sameTree(n, u0, wt0, u1, wt1); err != nil {
+			t.Fatal("NeighborJoinFast, n =", n, err)
+		}
+		u2, wt2 := d.RapidNJ()
+		if err := sameTree(n, u0, wt0, u2, wt2); err != nil {
+			t.Fatal("RapidNJ, n =", n, err)
+		}
+	}
+}
+
+func ExampleDistanceMatrix_BIONJ() {
+	d := cluster.DistanceMatrix{
+		{0, 5, 10, 9, 8},
+		{5, 0, 10, 10, 8},
+		{10, 10, 0, 8, 7},
+		{9, 10, 8, 0, 3},
+		{8, 8, 7, 3, 0},
+	}
+	// the matrix is not additive, so the variants give different lengths
+	for _, f := range []func() (graph.LabeledUndirected, []float64){
+		d.NeighborJoin, d.BIONJ, d.UNJ,
+	} {
+		u, wt := f()
+		cluster.WriteNewickUnrooted(os.Stdout, u, wt, []string{"a", "b", "c", "d", "e"})
+	}
+	// Output:
+	// (((e:0.875,d:2.125):1.625,c:4.375):3.125,a:2.3333333333333335,b:2.6666666666666665);
+	// (((e:0.8831852791878173,d:2.116814720812183):1.6166666666666663,c:4.383333333333334):3.1277777777777773,a:2.3333333333333335,b:2.6666666666666665);
+	// (((e:0.8333333333333335,d:2.1666666666666665):1.625,c:4.375):3.125,a:2.3333333333333335,b:2.6666666666666665);
+}
+
+// TestNJVariantsAdditive checks that all variants recover the tree of an
+// additive matrix.
+func TestNJVariantsAdditive(t *testing.T) {
+	for _, n := range []int{3, 5, 20, 60} {
+		d := cluster.RandomAdditiveMatrix(n)
+		u0, wt0 := d.AdditiveTree()
+		for name, f := range map[string]func() (graph.LabeledUndirected, []float64){
+			"NeighborJoinFast": d.NeighborJoinFast,
+			"RapidNJ":          d.RapidNJ,
+			"BIONJ":            d.BIONJ,
+			"UNJ":              d.UNJ,
+		} {
+			u, wt := f()
+			if err := sameTree(n, u0, wt0, u, wt); err != nil {
+				t.Fatal(name, ", n =", n, err)
+			}
+		}
+	}
+}
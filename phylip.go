@@ -0,0 +1,214 @@
+// Public domain.
+
+package cluster
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PhylipStrict, PhylipRelaxed constants for the name mode argument of
+// PHYLIP functions.
+const (
+	PhylipStrict  = iota // names occupy exactly the first 10 columns
+	PhylipRelaxed        // names are whitespace delimited, any length
+)
+
+// ReadPhylipDist reads a distance matrix in PHYLIP format.
+//
+// The first line holds the number of taxa n.  Each following row holds a
+// name and distances.  Rows of a square matrix hold n distances.  Rows of
+// a lower-triangular matrix, without diagonal, hold i distances for row i.
+// Distances of a row may continue on following lines.  Blank lines are
+// ignored, as is any input following the n rows.
+//
+// The format is recognized by the number of distances.  Input is read as a
+// square matrix if it holds rows of n distances, otherwise as a
+// lower-triangular matrix.  Errors for input that is neither are reported
+// for the square format, unless the first row has no distances.
+//
+// With mode PhylipStrict, the name is the first 10 characters of the row,
+// less trailing blanks, and distances may follow without separation.  With
+// mode PhylipRelaxed, the name is the first whitespace delimited field.
+//
+// Lower-triangular input is mirrored to fill the upper triangle.  The
+// result, of either format, is then checked with the same test as
+// DistanceMatrix.Symmetric.  NaN distances, in particular, are reported as
+// asymmetric.
+//
+// Errors are returned as *ParseError.
+func ReadPhylipDist(r io.Reader, mode int) (d DistanceMatrix, names []string, err error) {
+	sc := bufio.NewScanner(r)
+	line := 0
+	errorf := func(format string, a ...interface{}) error {
+		if err := sc.Err(); err != nil {
+			return err
+		}
+		return &ParseError{"phylip", line, fmt.Sprintf(format, a...)}
+	}
+	type text struct {
+		s    string
+		line int
+	}
+	var lines []text // non-blank lines
+	for sc.Scan() {
+		line++
+		if t := sc.Text(); strings.TrimSpace(t) != "" {
+			lines = append(lines, text{t, line})
+		}
+	}
+	eof := line
+	if len(lines) == 0 {
+		return nil, nil, errorf("no data")
+	}
+	line = lines[0].line
+	f := strings.Fields(lines[0].s)
+	n, err := strconv.Atoi(f[0])
+	if err != nil || n < 0 {
+		return nil, nil, errorf("invalid number of taxa %q", f[0])
+	}
+	lines = lines[1:]
+	values := func(s string, row []float64) ([]float64, error) {
+		for _, f := range strings.Fields(s) {
+			x, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, errorf("invalid distance %q", f)
+			}
+			row = append(row, x)
+		}
+		return row, nil
+	}
+	firstEmpty := false       // first row has no distances on its line
+	rowLine := make([]int, n) // line of the name of each row
+	parse := func(lower bool) (d DistanceMatrix, names []string, err error) {
+		d = make(DistanceMatrix, n)
+		names = make([]string, n)
+		x := 0 // index into lines
+		for i := range d {
+			if x == len(lines) {
+				line = eof
+				return nil, nil, errorf("unexpected end of input, "+
+					"expected row %d of %d", i+1, n)
+			}
+			t := lines[x].s
+			line = lines[x].line
+			rowLine[i] = line
+			x++
+			var rest string
+			if mode == PhylipStrict {
+				if len(t) > 10 {
+					names[i], rest = strings.TrimRight(t[:10], " \t"), t[10:]
+				} else {
+					names[i] = strings.TrimRight(t, " \t")
+				}
+			} else {
+				t = strings.TrimLeft(t, " \t")
+				k := strings.IndexAny(t, " \t")
+				if k < 0 {
+					k = len(t)
+				}
+				names[i], rest = t[:k], t[k:]
+			}
+			row, err := values(rest, make([]float64, 0, n))
+			if err != nil {
+				return nil, nil, err
+			}
+			if i == 0 {
+				firstEmpty = len(row) == 0
+			}
+			want := n
+			if lower {
+				want = i
+			}
+			for len(row) < want {
+				if x == len(lines) {
+					line = eof
+					return nil, nil, errorf("unexpected end of input, "+
+						"row %d has %d distances, expected %d", i+1, len(row), want)
+				}
+				line = lines[x].line
+				if row, err = values(lines[x].s, row); err != nil {
+					return nil, nil, err
+				}
+				x++
+			}
+			if len(row) > want {
+				return nil, nil, errorf("row %d has %d distances, expected %d",
+					i+1, len(row), want)
+			}
+			d[i] = make([]float64, n)
+			copy(d[i], row)
+		}
+		return d, names, nil
+	}
+	if d, names, err = parse(false); err != nil {
+		if _, ok := err.(*ParseError); !ok || n < 2 {
+			return nil, nil, err
+		}
+		squareErr, squareFirstEmpty := err, firstEmpty
+		if d, names, err = parse(true); err != nil {
+			if !squareFirstEmpty {
+				err = squareErr
+			}
+			return nil, nil, err
+		}
+		for i, di := range d {
+			for j, dij := range di[:i] {
+				d[j][i] = dij
+			}
+		}
+	}
+	for i, di := range d {
+		for j, dij := range di[:i] {
+			if !(dij == d[j][i]) {
+				line = rowLine[i]
+				return nil, nil, errorf("not symmetric: "+
+					"d[%d][%d] = %g, d[%d][%d] = %g", i, j, dij, j, i, d[j][i])
+			}
+		}
+	}
+	return d, names, nil
+}
+
+// WritePhylipDist writes a distance matrix in PHYLIP format.
+//
+// Argument names must have a name for each row of d.  With mode PhylipStrict
+// names are padded to 10 characters and a name longer than 10 characters is
+// an error.  With mode PhylipRelaxed a name containing whitespace is an
+// error.  If lower is true, only the lower triangle is written, without the
+// diagonal.  Otherwise the full square matrix is written.
+func WritePhylipDist(w io.Writer, d DistanceMatrix, names []string, lower bool, mode int) error {
+	if len(names) != len(d) {
+		return fmt.Errorf("%d names for %d rows", len(names), len(d))
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%5d\n", len(d))
+	for i, di := range d {
+		nm := names[i]
+		if mode == PhylipStrict {
+			if len(nm) > 10 {
+				return fmt.Errorf("name %q longer than 10 characters", nm)
+			}
+			fmt.Fprintf(&b, "%-10s", nm)
+		} else {
+			if nm == "" || strings.ContainsAny(nm, " \t\r\n") {
+				return fmt.Errorf("name %q empty or contains whitespace", nm)
+			}
+			b.WriteString(nm)
+		}
+		if lower {
+			di = di[:i]
+		}
+		for _, dij := range di {
+			b.WriteByte(' ')
+			b.WriteString(strconv.FormatFloat(dij, 'f', -1, 64))
+		}
+		b.WriteByte('\n')
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,172 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleReadPhylipDist() {
+	square := `    4
+Alpha     0 13 21 22
+Beta      13 0 12 13
+Gamma     21 12 0
+          13
+Delta     22 13 13 0
+`
+	d, names, err := cluster.ReadPhylipDist(strings.NewReader(square),
+		cluster.PhylipStrict)
+	fmt.Println(names, err)
+	fmt.Println(d)
+
+	lower := `4
+Homo_sapiens
+Pan_troglodytes 13
+Gorilla 21 12
+Pongo 22 13 13
+`
+	d, names, err = cluster.ReadPhylipDist(strings.NewReader(lower),
+		cluster.PhylipRelaxed)
+	fmt.Println(names, err)
+	fmt.Println(d)
+
+	bad := `3
+a 0 1 2
+b 1 0 x
+c 2 3 0
+`
+	_, _, err = cluster.ReadPhylipDist(strings.NewReader(bad),
+		cluster.PhylipRelaxed)
+	fmt.Println(err)
+	bad = `3
+a 0 1 2
+b 1 0 3
+c 2 4 0
+`
+	_, _, err = cluster.ReadPhylipDist(strings.NewReader(bad),
+		cluster.PhylipRelaxed)
+	fmt.Println(err)
+	// Output:
+	// [Alpha Beta Gamma Delta] <nil>
+	// [0 13 21 22]
+	// [13 0 12 13]
+	// [21 12 0 13]
+	// [22 13 13 0]
+	// [Homo_sapiens Pan_troglodytes Gorilla Pongo] <nil>
+	// [0 13 21 22]
+	// [13 0 12 13]
+	// [21 12 0 13]
+	// [22 13 13 0]
+	// phylip line 3: invalid distance "x"
+	// phylip line 4: not symmetric: d[2][1] = 4, d[1][2] = 3
+}
+
+func ExampleWritePhylipDist() {
+	d := cluster.DistanceMatrix{
+		{0, 1.5, 2},
+		{1.5, 0, 3},
+		{2, 3, 0},
+	}
+	names := []string{"a", "b", "c"}
+	cluster.WritePhylipDist(os.Stdout, d, names, false, cluster.PhylipStrict)
+	cluster.WritePhylipDist(os.Stdout, d, names, true, cluster.PhylipRelaxed)
+	// Output:
+	//     3
+	// a          0 1.5 2
+	// b          1.5 0 3
+	// c          2 3 0
+	//     3
+	// a
+	// b 1.5
+	// c 2 3
+}
+
+func TestPhylipRoundTrip(t *testing.T) {
+	d := cluster.RandomAdditiveMatrix(15)
+	names := make([]string, len(d))
+	for i := range names {
+		names[i] = fmt.Sprint("taxon", i)
+	}
+	for _, mode := range []int{cluster.PhylipStrict, cluster.PhylipRelaxed} {
+		for _, lower := range []bool{false, true} {
+			var b bytes.Buffer
+			if err := cluster.WritePhylipDist(&b, d, names, lower, mode); err != nil {
+				t.Fatal(err)
+			}
+			d2, names2, err := cluster.ReadPhylipDist(&b, mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d2.String() != d.String() ||
+				fmt.Sprint(names2) != fmt.Sprint(names) {
+				t.Fatal("round trip mismatch, mode", mode, "lower", lower)
+			}
+		}
+	}
+}
+
+func TestReadPhylipDistContinued(t *testing.T) {
+	// square, with distances of the first row on the following line
+	for _, mode := range []int{cluster.PhylipStrict, cluster.PhylipRelaxed} {
+		d, names, err := cluster.ReadPhylipDist(strings.NewReader(`3
+a
+          0 1 2
+b         1 0 3
+c         2 3 0
+`), mode)
+		if err != nil {
+			t.Fatal(mode, err)
+		}
+		if fmt.Sprint(names) != "[a b c]" ||
+			d.String() != "[0 1 2]\n[1 0 3]\n[2 3 0]" {
+			t.Fatal(mode, names, d)
+		}
+	}
+	// lower-triangular, with a row continued
+	d, _, err := cluster.ReadPhylipDist(strings.NewReader(`3
+a
+b 1
+c 2
+  3
+`), cluster.PhylipRelaxed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.String() != "[0 1 2]\n[1 0 3]\n[2 3 0]" {
+		t.Fatal(d)
+	}
+	for _, di := range d {
+		if cap(di) != len(d) {
+			t.Fatal("row capacity", cap(di))
+		}
+	}
+	// neither format, reported for the lower-triangular format
+	_, _, err = cluster.ReadPhylipDist(strings.NewReader(`3
+a
+b 1
+c 2 3 4
+`), cluster.PhylipRelaxed)
+	if err == nil || !strings.Contains(err.Error(), "row 3 has 3 distances, expected 2") {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPhylipDistNaN(t *testing.T) {
+	for _, in := range []string{
+		"3\nA\nB NaN\nC 2 3\n",
+		"3\nA 0 NaN 2\nB NaN 0 3\nC 2 3 0\n",
+	} {
+		_, _, err := cluster.ReadPhylipDist(strings.NewReader(in),
+			cluster.PhylipRelaxed)
+		pe, ok := err.(*cluster.ParseError)
+		if !ok || !strings.Contains(err.Error(), "not symmetric") || pe.Line != 3 {
+			t.Fatalf("%q: %v", in, err)
+		}
+	}
+}
@@ -0,0 +1,427 @@
+// Public domain.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Transform is a preprocessing step for points, as may be applied before
+// KMeans, KMPP, or NewPearsonSim.
+//
+// Apply returns a transformed copy of p.  The argument is not modified.
+//
+// Transforms are fitted to a set of points by functions such as FitZScore
+// and FitPCA.  A fitted transform holds its parameters in exported fields
+// and so can be saved, for example with encoding/json, and later applied
+// to new points.  A Pipeline, which holds transforms as interface values,
+// is saved and restored with its MarshalJSON and UnmarshalJSON methods.
+type Transform interface {
+	Apply(p Point) Point
+}
+
+// TransformAll applies t to each of pts, returning the transformed points.
+func TransformAll(t Transform, pts []Point) []Point {
+	r := make([]Point, len(pts))
+	for i, p := range pts {
+		r[i] = t.Apply(p)
+	}
+	return r
+}
+
+// Pipeline is a sequence of transforms applied in order.
+//
+// To fit a pipeline, fit each step to the points as transformed by the
+// steps before it.
+type Pipeline []Transform
+
+// Apply applies each transform of the pipeline in turn.
+func (pl Pipeline) Apply(p Point) Point {
+	p = append(Point{}, p...)
+	for _, t := range pl {
+		p = t.Apply(p)
+	}
+	return p
+}
+
+// pipelineStep is the JSON form of a transform of a Pipeline.
+type pipelineStep struct {
+	Type   string
+	Params json.RawMessage `json:",omitempty"`
+}
+
+// MarshalJSON encodes the pipeline as a JSON array with an object for each
+// transform, giving its type and fitted parameters.
+//
+// Transforms must be of the types of this package, Log1p, *ZScore,
+// *MinMax, *Quantile, *PCA, or Pipeline.  Other types are an error.
+func (pl Pipeline) MarshalJSON() ([]byte, error) {
+	steps := make([]pipelineStep, len(pl))
+	for i, t := range pl {
+		var s pipelineStep
+		switch t.(type) {
+		case Log1p:
+			s.Type = "Log1p"
+		case *ZScore:
+			s.Type = "ZScore"
+		case *MinMax:
+			s.Type = "MinMax"
+		case *Quantile:
+			s.Type = "Quantile"
+		case *PCA:
+			s.Type = "PCA"
+		case Pipeline:
+			s.Type = "Pipeline"
+		default:
+			return nil, fmt.Errorf("pipeline step %d: unsupported type %T", i, t)
+		}
+		if s.Type != "Log1p" {
+			var err error
+			if s.Params, err = json.Marshal(t); err != nil {
+				return nil, err
+			}
+		}
+		steps[i] = s
+	}
+	return json.Marshal(steps)
+}
+
+// UnmarshalJSON decodes a pipeline encoded by MarshalJSON.
+func (pl *Pipeline) UnmarshalJSON(b []byte) error {
+	var steps []pipelineStep
+	if err := json.Unmarshal(b, &steps); err != nil {
+		return err
+	}
+	r := make(Pipeline, len(steps))
+	for i, s := range steps {
+		var t Transform
+		switch s.Type {
+		case "Log1p":
+			r[i] = Log1p{}
+			continue
+		case "ZScore":
+			t = &ZScore{}
+		case "MinMax":
+			t = &MinMax{}
+		case "Quantile":
+			t = &Quantile{}
+		case "PCA":
+			t = &PCA{}
+		case "Pipeline":
+			t = &Pipeline{}
+		default:
+			return fmt.Errorf("pipeline step %d: unknown type %q", i, s.Type)
+		}
+		if err := json.Unmarshal(s.Params, t); err != nil {
+			return err
+		}
+		if p, ok := t.(*Pipeline); ok {
+			t = *p
+		}
+		r[i] = t
+	}
+	*pl = r
+	return nil
+}
+
+// meanPoint returns the mean of pts.
+func meanPoint(pts []Point) Point {
+	m := make(Point, len(pts[0]))
+	for _, p := range pts {
+		m.Add(p)
+	}
+	m.Mul(1 / float64(len(pts)))
+	return m
+}
+
+// ZScore standardizes each coordinate to mean 0 and standard deviation 1.
+type ZScore struct {
+	Mean Point // mean of each coordinate
+	SD   Point // population standard deviation of each coordinate
+}
+
+// FitZScore returns a ZScore transform fitted to pts.
+func FitZScore(pts []Point) *ZScore {
+	z := &ZScore{Mean: meanPoint(pts), SD: make(Point, len(pts[0]))}
+	for _, p := range pts {
+		for i, x := range p {
+			d := x - z.Mean[i]
+			z.SD[i] += d * d
+		}
+	}
+	for i, s := range z.SD {
+		z.SD[i] = math.Sqrt(s / float64(len(pts)))
+	}
+	return z
+}
+
+// Apply returns (p - Mean) / SD by coordinate.  Coordinates with SD 0
+// become 0.
+func (z *ZScore) Apply(p Point) Point {
+	r := make(Point, len(p))
+	for i, x := range p {
+		if s := z.SD[i]; s > 0 {
+			r[i] = (x - z.Mean[i]) / s
+		}
+	}
+	return r
+}
+
+// MinMax scales each coordinate to the range 0 to 1.
+type MinMax struct {
+	Min Point // minimum of each coordinate
+	Max Point // maximum of each coordinate
+}
+
+// FitMinMax returns a MinMax transform fitted to pts.
+func FitMinMax(pts []Point) *MinMax {
+	m := &MinMax{
+		Min: append(Point{}, pts[0]...),
+		Max: append(Point{}, pts[0]...),
+	}
+	for _, p := range pts[1:] {
+		for i, x := range p {
+			m.Min[i] = math.Min(m.Min[i], x)
+			m.Max[i] = math.Max(m.Max[i], x)
+		}
+	}
+	return m
+}
+
+// Apply returns (p - Min) / (Max - Min) by coordinate.  Coordinates of
+// new points outside the fitted range fall outside 0 to 1.  Coordinates
+// with Max = Min become 0.
+func (m *MinMax) Apply(p Point) Point {
+	r := make(Point, len(p))
+	for i, x := range p {
+		if w := m.Max[i] - m.Min[i]; w > 0 {
+			r[i] = (x - m.Min[i]) / w
+		}
+	}
+	return r
+}
+
+// Log1p transforms each coordinate x to log(1 + x).
+//
+// It needs no fitting.
+type Log1p struct{}
+
+// Apply returns log(1 + x) for each coordinate x of p.
+func (Log1p) Apply(p Point) Point {
+	r := make(Point, len(p))
+	for i, x := range p {
+		r[i] = math.Log1p(x)
+	}
+	return r
+}
+
+// Quantile normalizes the coordinates of each point to a common
+// distribution.
+//
+// This is quantile normalization as commonly used for expression data,
+// where a point holds the values measured in one sample.  Each coordinate
+// is replaced by the value of the reference distribution at the rank of
+// the coordinate within the point.
+type Quantile struct {
+	Ref []float64 // reference distribution, in increasing order
+}
+
+// FitQuantile returns a Quantile transform fitted to pts.
+//
+// The reference distribution is the mean over pts of the sorted
+// coordinates.
+func FitQuantile(pts []Point) *Quantile {
+	q := &Quantile{Ref: make([]float64, len(pts[0]))}
+	s := make([]float64, len(q.Ref))
+	for _, p := range pts {
+		copy(s, p)
+		sort.Float64s(s)
+		for i, x := range s {
+			q.Ref[i] += x
+		}
+	}
+	for i := range q.Ref {
+		q.Ref[i] /= float64(len(pts))
+	}
+	return q
+}
+
+// Apply returns p with coordinates replaced by reference values by rank.
+// Tied coordinates take the mean of the reference values over their ranks.
+func (q *Quantile) Apply(p Point) Point {
+	order := make([]int, len(p))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return p[order[i]] < p[order[j]] })
+	r := make(Point, len(p))
+	for lo := 0; lo < len(order); {
+		hi := lo + 1
+		for hi < len(order) && p[order[hi]] == p[order[lo]] {
+			hi++
+		}
+		m := 0.
+		for _, x := range q.Ref[lo:hi] {
+			m += x
+		}
+		m /= float64(hi - lo)
+		for _, i := range order[lo:hi] {
+			r[i] = m
+		}
+		lo = hi
+	}
+	return r
+}
+
+// PCASVD, PCAPower constants for the solver argument of FitPCA.
+const (
+	PCASVD   = iota // singular value decomposition
+	PCAPower        // power iteration
+)
+
+// PCA projects points onto principal components.
+type PCA struct {
+	Mean       Point     // mean of the fitted points
+	Components []Point   // unit principal axes, in decreasing order of variance
+	Variance   []float64 // variance along each component
+	Total      float64   // total variance of the fitted points
+}
+
+// FitPCA returns a PCA transform to the first k principal components of
+// pts.
+//
+// With solver PCASVD, components are found by a singular value
+// decomposition of the centered points by the one-sided Jacobi method, in
+// O(n m^2) time per sweep for n points of dimension m.  With PCAPower,
+// the k components are found one at a time by power iteration with
+// deflation, in O(n m) time per iteration.  Power iteration is faster when
+// k is much less than m but converges slowly where successive variances
+// are nearly equal.
+//
+// Variances are sample variances, with denominator n-1.  The sign of each
+// component is chosen so that its coordinate of largest magnitude is
+// positive.  The fraction of variance explained by component c is
+// Variance[c] / Total.
+//
+// Argument k must be no more than the dimension of the points and pts must
+// have at least two points.
+func FitPCA(pts []Point, k, solver int) *PCA {
+	m := len(pts[0])
+	t := &PCA{Mean: meanPoint(pts)}
+	x := make([][]float64, len(pts)) // centered points
+	for i, p := range pts {
+		xi := make([]float64, m)
+		for j, pj := range p {
+			xi[j] = pj - t.Mean[j]
+			t.Total += xi[j] * xi[j]
+		}
+		x[i] = xi
+	}
+	nm1 := float64(len(pts) - 1)
+	t.Total /= nm1
+	if solver == PCAPower {
+		t.Components, t.Variance = powerPCA(x, k)
+	} else {
+		sv, vecs := rightSingular(x)
+		for c, v := range vecs[:k] {
+			t.Components = append(t.Components, v)
+			t.Variance = append(t.Variance, sv[c]*sv[c])
+		}
+	}
+	for c, v := range t.Components {
+		t.Variance[c] /= nm1
+		big := 0
+		for j, vj := range v {
+			if math.Abs(vj) > math.Abs(v[big]) {
+				big = j
+			}
+		}
+		if v[big] < 0 {
+			v.Mul(-1)
+		}
+	}
+	return t
+}
+
+// powerPCA returns the k leading eigenvectors of x'x, and the
+// corresponding eigenvalues, by power iteration with deflation.
+func powerPCA(x [][]float64, k int) (vecs []Point, vals []float64) {
+	m := len(x[0])
+	xv := make([]float64, len(x))
+	// mul returns x'x v
+	mul := func(v Point) Point {
+		for i, xi := range x {
+			s := 0.
+			for j, xij := range xi {
+				s += xij * v[j]
+			}
+			xv[i] = s
+		}
+		w := make(Point, m)
+		for i, xi := range x {
+			for j, xij := range xi {
+				w[j] += xij * xv[i]
+			}
+		}
+		return w
+	}
+	// deflate removes components of v along vecs found so far
+	deflate := func(v Point) {
+		for _, u := range vecs {
+			d := 0.
+			for j, uj := range u {
+				d += uj * v[j]
+			}
+			for j, uj := range u {
+				v[j] -= d * uj
+			}
+		}
+	}
+	norm := func(v Point) float64 {
+		return math.Sqrt(v.Sqd(make(Point, len(v))))
+	}
+	for c := 0; c < k; c++ {
+		v := make(Point, m)
+		for j := range v {
+			// fixed start, unlikely to be orthogonal to any eigenvector
+			v[j] = 1 + math.Mod(float64(j+c)*.6180339887, 1)
+		}
+		deflate(v)
+		v.Mul(1 / norm(v))
+		λ := 0.
+		for it := 0; it < 10000; it++ {
+			w := mul(v)
+			deflate(w)
+			λ = norm(w)
+			if λ == 0 {
+				break
+			}
+			w.Mul(1 / λ)
+			d := 0.
+			for j, wj := range w {
+				d += wj * v[j]
+			}
+			v = w
+			if 1-math.Abs(d) < 1e-15 {
+				break
+			}
+		}
+		vecs = append(vecs, v)
+		vals = append(vals, λ)
+	}
+	return
+}
+
+// Apply returns the coordinates of p - Mean along the components.
+func (t *PCA) Apply(p Point) Point {
+	r := make(Point, len(t.Components))
+	for c, v := range t.Components {
+		for j, vj := range v {
+			r[c] += vj * (p[j] - t.Mean[j])
+		}
+	}
+	return r
+}
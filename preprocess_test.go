@@ -0,0 +1,175 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleFitZScore() {
+	pts := []cluster.Point{{1, 10, 5}, {2, 20, 5}, {3, 30, 5}}
+	z := cluster.FitZScore(pts)
+	for _, p := range cluster.TransformAll(z, pts) {
+		fmt.Printf("%.3f\n", p)
+	}
+	// a new point, standardized with the fitted mean and deviation
+	fmt.Printf("%.3f\n", z.Apply(cluster.Point{4, 0, 5}))
+	// Output:
+	// [-1.225 -1.225 0.000]
+	// [0.000 0.000 0.000]
+	// [1.225 1.225 0.000]
+	// [2.449 -2.449 0.000]
+}
+
+func ExampleFitMinMax() {
+	pts := []cluster.Point{{1, 10}, {3, 30}, {2, 20}}
+	m := cluster.FitMinMax(pts)
+	for _, p := range cluster.TransformAll(m, pts) {
+		fmt.Println(p)
+	}
+	fmt.Println(m.Apply(cluster.Point{4, 15}))
+	// Output:
+	// [0 0]
+	// [1 1]
+	// [0.5 0.5]
+	// [1.5 0.25]
+}
+
+func ExampleFitQuantile() {
+	// three samples of four genes
+	pts := []cluster.Point{{5, 2, 3, 4}, {4, 1, 4, 2}, {3, 4, 6, 8}}
+	q := cluster.FitQuantile(pts)
+	fmt.Printf("%.3f\n", q.Ref)
+	for _, p := range cluster.TransformAll(q, pts) {
+		fmt.Printf("%.3f\n", p)
+	}
+	// Output:
+	// [2.000 3.000 4.667 5.667]
+	// [5.667 2.000 3.000 4.667]
+	// [5.167 2.000 5.167 3.000]
+	// [2.000 3.000 4.667 5.667]
+}
+
+func ExampleFitPCA() {
+	// points near the line y = 2x
+	pts := []cluster.Point{{0, 0}, {1, 2.1}, {2, 3.9}, {3, 6}, {4, 8}}
+	t := cluster.FitPCA(pts, 1, cluster.PCASVD)
+	fmt.Printf("%.3f\n", t.Components)
+	fmt.Printf("%.4f\n", t.Variance[0]/t.Total)
+	for _, p := range cluster.TransformAll(t, pts) {
+		fmt.Printf("%.3f\n", p)
+	}
+	// Output:
+	// [[0.449 0.894]]
+	// 0.9999
+	// [-4.472]
+	// [-2.147]
+	// [-0.089]
+	// [2.236]
+	// [4.472]
+}
+
+func ExamplePipeline() {
+	pts := []cluster.Point{{0, 9, 99}, {1, 19, 199}, {3, 39, 399}, {7, 79, 799}}
+	// fit each step to the output of the steps before it
+	pl := cluster.Pipeline{cluster.Log1p{}}
+	pl = append(pl, cluster.FitZScore(cluster.TransformAll(pl, pts)))
+	pl = append(pl, cluster.FitPCA(cluster.TransformAll(pl, pts), 1, cluster.PCAPower))
+	// save the fitted pipeline, restore it, apply it to new data
+	b, err := json.Marshal(pl)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	var fresh cluster.Pipeline
+	if err := json.Unmarshal(b, &fresh); err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, p := range pts {
+		fmt.Printf("%.3f %.3f\n", pl.Apply(p), fresh.Apply(p))
+	}
+	// Output:
+	// [-2.324] [-2.324]
+	// [-0.775] [-0.775]
+	// [0.775] [0.775]
+	// [2.324] [2.324]
+}
+
+func TestFitPCA(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var pts []cluster.Point
+	for i := 0; i < 50; i++ {
+		p := make(cluster.Point, 6)
+		for j := range p {
+			p[j] = float64(6-j) * rnd.NormFloat64()
+		}
+		p[5] += p[0] // correlated coordinates
+		pts = append(pts, p)
+	}
+	s := cluster.FitPCA(pts, 3, cluster.PCASVD)
+	p := cluster.FitPCA(pts, 3, cluster.PCAPower)
+	for c := range s.Components {
+		if math.Abs(s.Variance[c]-p.Variance[c]) > 1e-9*s.Total {
+			t.Fatal("variance", c, s.Variance[c], p.Variance[c])
+		}
+		if c > 0 && s.Variance[c] > s.Variance[c-1] {
+			t.Fatal("variance order", s.Variance)
+		}
+		if d := s.Components[c].Sqd(p.Components[c]); d > 1e-12 {
+			t.Fatal("component", c, s.Components[c], p.Components[c])
+		}
+	}
+	// full rotation preserves total variance and distances
+	f := cluster.FitPCA(pts, 6, cluster.PCASVD)
+	sum := 0.
+	for _, v := range f.Variance {
+		sum += v
+	}
+	if math.Abs(sum-f.Total) > 1e-9*f.Total {
+		t.Fatal("total", sum, f.Total)
+	}
+	r := cluster.TransformAll(f, pts)
+	if d, e := pts[0].Sqd(pts[1]), r[0].Sqd(r[1]); math.Abs(d-e) > 1e-9*d {
+		t.Fatal("distance", d, e)
+	}
+}
+
+type scale float64
+
+func (s scale) Apply(p cluster.Point) cluster.Point {
+	r := append(cluster.Point{}, p...)
+	r.Mul(float64(s))
+	return r
+}
+
+func TestPipelineJSON(t *testing.T) {
+	pts := []cluster.Point{{1, 5}, {2, 3}, {4, 4}}
+	inner := cluster.Pipeline{cluster.FitMinMax(pts), cluster.FitQuantile(pts)}
+	pl := cluster.Pipeline{cluster.Log1p{}, inner}
+	b, err := json.Marshal(pl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r cluster.Pipeline
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pts {
+		if x, y := pl.Apply(p), r.Apply(p); x.Sqd(y) != 0 {
+			t.Fatal(x, y)
+		}
+	}
+	if _, err := json.Marshal(cluster.Pipeline{scale(2)}); err == nil {
+		t.Fatal("no error for unsupported transform")
+	}
+	if err := json.Unmarshal([]byte(`[{"Type":"Scale"}]`), &r); err == nil {
+		t.Fatal("no error for unknown type")
+	}
+}
@@ -0,0 +1,233 @@
+// Public domain.
+
+package cluster
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/soniakeys/graph"
+)
+
+// RandomTopology returns a random unrooted binary tree topology.
+//
+// All labeled topologies on nLeaves leaves are equally likely.  The tree is
+// returned in the representation of NeighborJoin, with leaves as nodes
+// 0:nLeaves, internal nodes of degree three following, and edges labeled
+// 0:2*nLeaves-3.  Random numbers are taken from rnd.
+func RandomTopology(nLeaves int, rnd *rand.Rand) graph.LabeledUndirected {
+	var a graph.LabeledAdjacencyList
+	edge := func(n1, n2 graph.NI, l graph.LI) {
+		a[n1] = append(a[n1], graph.Half{To: n2, Label: l})
+		a[n2] = append(a[n2], graph.Half{To: n1, Label: l})
+	}
+	switch {
+	case nLeaves < 2:
+		a = make(graph.LabeledAdjacencyList, nLeaves)
+	case nLeaves == 2:
+		a = make(graph.LabeledAdjacencyList, 2)
+		edge(0, 1, 0)
+	default:
+		pl := randomUTree(nLeaves, rnd.Intn)
+		a = make(graph.LabeledAdjacencyList, len(pl)+1)
+		for n, p := range pl {
+			edge(graph.NI(n), graph.NI(p), graph.LI(n))
+		}
+	}
+	return graph.LabeledUndirected{LabeledAdjacencyList: a}
+}
+
+// RandomYule returns a random rooted binary tree under the Yule pure birth
+// process.
+//
+// Starting from a root with two lineages, each lineage splits at rate
+// birth until there are nLeaves lineages.  The process then runs for a
+// further random waiting time, so that leaf branches are not of zero
+// length.  The tree is returned as a parent list and labels in the form of
+// DistanceMatrix.Ultrametric, with leaves 0:nLeaves in random order,
+// internal nodes in order of increasing age, and the root last.  Random
+// numbers are taken from rnd.
+func RandomYule(nLeaves int, birth float64, rnd *rand.Rand) (graph.FromList, []Ultrametric) {
+	if nLeaves < 2 {
+		return randomSingleton(nLeaves)
+	}
+	// internal nodes are numbered in order of creation, then renumbered
+	// so the root is last.
+	from := []int{-1} // parent of internal node
+	times := []float64{0}
+	type lineage struct{ parent int }
+	active := []lineage{{0}, {0}}
+	t := 0.
+	for len(active) < nLeaves {
+		t += rnd.ExpFloat64() / (birth * float64(len(active)))
+		i := rnd.Intn(len(active))
+		from = append(from, active[i].parent)
+		times = append(times, t)
+		active[i].parent = len(times) - 1
+		active = append(active, lineage{len(times) - 1})
+	}
+	t += rnd.ExpFloat64() / (birth * float64(nLeaves))
+	nn := 2*nLeaves - 1
+	node := func(c int) graph.NI { return graph.NI(nn - 1 - c) }
+	pl := make([]graph.PathEnd, nn)
+	ul := make([]Ultrametric, nn)
+	for c, p := range from {
+		n := node(c)
+		ul[n].Age = t - times[c]
+		if p < 0 {
+			pl[n].From = -1
+			ul[n].Weight = math.NaN()
+		} else {
+			pl[n].From = node(p)
+			ul[n].Weight = times[c] - times[p]
+		}
+	}
+	for l, i := range rnd.Perm(nLeaves) {
+		p := active[i].parent
+		pl[l] = graph.PathEnd{From: node(p), Len: 1}
+		ul[l].Weight = t - times[p]
+	}
+	leafCounts(pl)
+	return graph.FromList{Paths: pl}, ul
+}
+
+// RandomCoalescent returns a random rooted binary tree under the Kingman
+// coalescent.
+//
+// Starting from nLeaves lineages at age 0, each pair of lineages coalesces
+// at rate 1/scale, so that while there are k lineages the waiting time to
+// the next coalescence is exponentially distributed with mean
+// 2*scale/(k(k-1)).  The tree is returned in the form of RandomYule.
+// Random numbers are taken from rnd.
+func RandomCoalescent(nLeaves int, scale float64, rnd *rand.Rand) (graph.FromList, []Ultrametric) {
+	if nLeaves < 2 {
+		return randomSingleton(nLeaves)
+	}
+	nn := 2*nLeaves - 1
+	pl := make([]graph.PathEnd, nn)
+	ul := make([]Ultrametric, nn)
+	active := make([]graph.NI, nLeaves)
+	for l := range active {
+		active[l] = graph.NI(l)
+		pl[l].Len = 1
+	}
+	t := 0.
+	for n := nLeaves; n < nn; n++ {
+		k := len(active)
+		t += rnd.ExpFloat64() * scale * 2 / float64(k*(k-1))
+		i := rnd.Intn(k)
+		j := rnd.Intn(k - 1)
+		if j >= i {
+			j++
+		}
+		for _, c := range []graph.NI{active[i], active[j]} {
+			pl[c].From = graph.NI(n)
+			ul[c].Weight = t - ul[c].Age
+			pl[n].Len += pl[c].Len
+		}
+		ul[n].Age = t
+		active[i] = graph.NI(n)
+		active[j] = active[k-1]
+		active = active[:k-1]
+	}
+	pl[nn-1].From = -1
+	ul[nn-1].Weight = math.NaN()
+	return graph.FromList{Paths: pl}, ul
+}
+
+// randomSingleton returns the tree of RandomYule or RandomCoalescent for
+// fewer than two leaves.
+func randomSingleton(nLeaves int) (graph.FromList, []Ultrametric) {
+	pl := make([]graph.PathEnd, nLeaves)
+	ul := make([]Ultrametric, nLeaves)
+	for n := range pl {
+		pl[n] = graph.PathEnd{From: -1, Len: 1}
+		ul[n].Weight = math.NaN()
+	}
+	return graph.FromList{Paths: pl}, ul
+}
+
+// leafCounts sets Len of each internal node of a rooted tree to the number
+// of leaves under the node.  Leaves must have Len 1 and internal nodes Len 0.
+func leafCounts(pl []graph.PathEnd) {
+	for _, p := range pl {
+		if p.Len != 1 {
+			continue
+		}
+		for a := p.From; a >= 0; a = pl[a].From {
+			pl[a].Len++
+		}
+	}
+}
+
+// RandomUltrametricMatrix constructs a random ultrametric distance matrix.
+//
+// The matrix holds the distances between leaves of a RandomCoalescent tree
+// with scale 1, which is also returned.  The distance between two leaves is
+// twice the age of their most recent common ancestor.  Random numbers are
+// taken from rnd.
+func RandomUltrametricMatrix(n int, rnd *rand.Rand) (DistanceMatrix, graph.FromList, []Ultrametric) {
+	pl, ul := RandomCoalescent(n, 1, rnd)
+	return copheneticDist(pl, ul, n), pl, ul
+}
+
+// copheneticDist returns distances between leaves of a rooted ultrametric
+// tree, twice the age of the most recent common ancestor.
+func copheneticDist(pl graph.FromList, ul []Ultrametric, nLeaves int) DistanceMatrix {
+	d := make(DistanceMatrix, nLeaves)
+	for i := range d {
+		d[i] = make([]float64, nLeaves)
+	}
+	under := make([][]int, len(pl.Paths)) // leaves under each node
+	for l := 0; l < nLeaves; l++ {
+		under[l] = []int{l}
+	}
+	// visit nodes in order of number, with children before parents as for
+	// the results of RandomCoalescent, RandomYule, and Ultrametric.
+	for c, p := range pl.Paths {
+		if p.From < 0 {
+			continue
+		}
+		a := 2 * ul[p.From].Age
+		for _, i := range under[p.From] {
+			for _, j := range under[c] {
+				d[i][j] = a
+				d[j][i] = a
+			}
+		}
+		under[p.From] = append(under[p.From], under[c]...)
+	}
+	return d
+}
+
+// RandomNoisyAdditiveMatrix constructs a random distance matrix close to an
+// additive matrix.
+//
+// The additive matrix holds the distances between leaves of a RandomTopology
+// tree with integer branch lengths drawn uniformly from 10 through 99, as
+// with RandomAdditiveMatrix.  Each distance is then multiplied by
+// exp(noise*z) with z drawn from a standard normal distribution, so noise is
+// roughly the standard deviation of the relative error.  With noise 0 the
+// matrix is additive.  The tree and branch lengths are also returned.
+// Random numbers are taken from rnd.
+func RandomNoisyAdditiveMatrix(n int, noise float64, rnd *rand.Rand) (d DistanceMatrix, u graph.LabeledUndirected, wt []float64) {
+	u = RandomTopology(n, rnd)
+	if n > 1 {
+		wt = make([]float64, 2*n-3)
+	}
+	for i := range wt {
+		wt[i] = float64(10 + rnd.Intn(90))
+	}
+	d = NewPatristicDist(u, wt, n)
+	for i, di := range d {
+		for j := 0; j < i; j++ {
+			x := di[j] // (also makes d exactly symmetric)
+			if noise != 0 {
+				x *= math.Exp(noise * rnd.NormFloat64())
+			}
+			di[j] = x
+			d[j][i] = x
+		}
+	}
+	return
+}
@@ -0,0 +1,143 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+	"github.com/soniakeys/graph"
+)
+
+func ExampleRandomUltrametricMatrix() {
+	rnd := rand.New(rand.NewSource(7))
+	d, pl, ul := cluster.RandomUltrametricMatrix(20, rnd)
+	// UPGMA recovers the coalescent tree from its ultrametric matrix.
+	pl2, ul2 := d.Clone().Ultrametric(cluster.DAVG)
+	fmt.Println(cluster.RobinsonFoulds(
+		cluster.Clusters(pl, ul, len(d)),
+		cluster.Clusters(pl2, ul2, len(d))))
+	// Output:
+	// 0
+}
+
+func ExampleRandomNoisyAdditiveMatrix() {
+	rnd := rand.New(rand.NewSource(7))
+	for _, noise := range []float64{0, .3} {
+		d, u, wt := cluster.RandomNoisyAdditiveMatrix(20, noise, rnd)
+		nj, njWt := d.NeighborJoin()
+		fmt.Println(noise, cluster.RobinsonFoulds(
+			cluster.Bipartitions(u, wt, len(d)),
+			cluster.Bipartitions(nj, njWt, len(d))))
+	}
+	// Output:
+	// 0 0
+	// 0.3 8
+}
+
+func TestRandomTopology(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 8; n++ {
+		u := cluster.RandomTopology(n, rnd)
+		a := u.LabeledAdjacencyList
+		if n > 1 && len(a) != 2*n-2 {
+			t.Fatal(n, "leaves,", len(a), "nodes")
+		}
+		seen := map[graph.LI]int{}
+		for x, hs := range a {
+			switch {
+			case n == 1:
+			case x < n && len(hs) != 1, x >= n && len(hs) != 3:
+				t.Fatal("node", x, "degree", len(hs))
+			}
+			for _, h := range hs {
+				seen[h.Label]++
+			}
+		}
+		for l := 0; l < 2*n-3; l++ {
+			if seen[graph.LI(l)] != 2 {
+				t.Fatal(n, "leaves, label", l, "seen", seen[graph.LI(l)])
+			}
+		}
+	}
+	// the three topologies on four leaves are about equally likely
+	count := map[string]int{}
+	const nt = 3000
+	for i := 0; i < nt; i++ {
+		u := cluster.RandomTopology(4, rnd)
+		for _, s := range cluster.Bipartitions(u, make([]float64, 5), 4) {
+			if !s.Trivial() {
+				count[s.Leaves.String()]++
+			}
+		}
+	}
+	if len(count) != 3 {
+		t.Fatal(count)
+	}
+	for k, c := range count {
+		if math.Abs(float64(c)-nt/3) > 150 {
+			t.Fatal(k, c)
+		}
+	}
+}
+
+func TestRandomRooted(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	gen := map[string]func(int) (graph.FromList, []cluster.Ultrametric){
+		"Yule": func(n int) (graph.FromList, []cluster.Ultrametric) {
+			return cluster.RandomYule(n, 2, rnd)
+		},
+		"coalescent": func(n int) (graph.FromList, []cluster.Ultrametric) {
+			return cluster.RandomCoalescent(n, 2, rnd)
+		},
+	}
+	for name, f := range gen {
+		for n := 1; n < 12; n++ {
+			pl, ul := f(n)
+			p := pl.Paths
+			if len(p) != 2*n-1 || p[len(p)-1].From != -1 ||
+				p[len(p)-1].Len != n {
+				t.Fatal(name, n, "bad root")
+			}
+			nc := make([]int, len(p))
+			for x := range p {
+				if x > 0 && ul[x].Age < ul[x-1].Age && x >= n {
+					t.Fatal(name, n, "ages not increasing")
+				}
+				if p[x].From >= 0 {
+					nc[p[x].From]++
+					if math.Abs(ul[x].Age+ul[x].Weight-ul[p[x].From].Age) > 1e-12 ||
+						!(ul[x].Weight > 0) {
+						t.Fatal(name, n, "bad weight at node", x)
+					}
+				}
+			}
+			for x := n; x < len(p); x++ {
+				if nc[x] != 2 {
+					t.Fatal(name, n, "node", x, "has", nc[x], "children")
+				}
+			}
+			for x := 0; x < n; x++ {
+				if ul[x].Age != 0 || p[x].Len != 1 {
+					t.Fatal(name, n, "bad leaf", x)
+				}
+			}
+		}
+	}
+}
+
+func TestRandomNoisyAdditiveMatrix(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	d, u, wt := cluster.RandomNoisyAdditiveMatrix(15, 0, rnd)
+	u2, wt2, err := d.AdditiveTreeChecked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs := cluster.BranchScore(cluster.Bipartitions(u, wt, 15),
+		cluster.Bipartitions(u2, wt2, 15)); bs > 1e-9 {
+		t.Fatal("branch score", bs)
+	}
+}
@@ -0,0 +1,186 @@
+// Public domain.
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/soniakeys/graph"
+)
+
+// Rooting of unrooted trees.
+//
+// The unrooted tree is given as an undirected graph with edge labels
+// indexing a weight list, as returned by NeighborJoin, AdditiveTree, or
+// ReadNewick.  Leaves must be nodes 0:nLeaves.
+//
+// The rooted tree is returned as a parent list and labels in the form
+// returned by DistanceMatrix.Ultrametric and accepted by NewDendrogram.
+// Node numbers of the unrooted tree are kept.  Where the root splits an
+// edge, the root is a new node numbered len(u.LabeledAdjacencyList).
+// PathEnd.Len is the number of leaves under each node.  Weight is the
+// branch length from the parent, NaN for the root.  Age is the greatest
+// path length from the node down to a leaf.  For an ultrametric tree Age is
+// the same as the age of Ultrametric results, but in general ages of
+// children do not sum with weights to the age of the parent.
+
+// RootMidpoint roots an unrooted tree at the midpoint of the longest path
+// between two leaves.
+//
+// If the midpoint falls on an internal node, that node becomes the root.
+// Otherwise the edge containing the midpoint is split in two, with a new
+// root node between the parts.  Among equally long paths, the first found
+// is used, scanning leaf pairs in order of the higher numbered leaf.
+func RootMidpoint(u graph.LabeledUndirected, wt []float64, nLeaves int) (graph.FromList, []Ultrametric) {
+	a := u.LabeledAdjacencyList
+	if nLeaves < 2 {
+		return rootTree(a, wt, nLeaves, 0, -1, 0, 0)
+	}
+	pd := NewPatristicDist(u, wt, nLeaves)
+	l1, l2 := 0, 1
+	for i, di := range pd {
+		for j, dij := range di[:i] {
+			if dij > pd[l1][l2] {
+				l1, l2 = j, i
+			}
+		}
+	}
+	// path from l2 back to l1, as nodes and edge labels
+	from := make([]graph.Half, len(a))
+	var f func(n, p graph.NI)
+	f = func(n, p graph.NI) {
+		for _, h := range a[n] {
+			if h.To != p {
+				from[h.To] = graph.Half{To: n, Label: h.Label}
+				f(h.To, n)
+			}
+		}
+	}
+	f(graph.NI(l1), -1)
+	half := .5 * pd[l1][l2]
+	run := 0. // path length from l2
+	n := graph.NI(l2)
+	for {
+		h := from[n]
+		w := wt[h.Label]
+		switch {
+		case run+w == half && int(h.To) >= nLeaves:
+			return rootTree(a, wt, nLeaves, h.To, -1, 0, 0)
+		case run+w >= half || int(h.To) == l1:
+			return rootTree(a, wt, nLeaves, n, h.To, half-run, run+w-half)
+		}
+		run += w
+		n = h.To
+	}
+}
+
+// RootOutgroup roots an unrooted tree on the edge separating a set of
+// outgroup leaves from the remaining leaves.
+//
+// The root splits the edge in half.  An error is returned if outgroup is
+// empty, contains all leaves or a node that is not a leaf, or if no edge
+// separates the outgroup from the other leaves.
+func RootOutgroup(u graph.LabeledUndirected, wt []float64, nLeaves int, outgroup []graph.NI) (graph.FromList, []Ultrametric, error) {
+	a := u.LabeledAdjacencyList
+	isOut := make([]bool, nLeaves)
+	nOut := 0
+	for _, l := range outgroup {
+		if l < 0 || int(l) >= nLeaves {
+			return graph.FromList{}, nil, fmt.Errorf("outgroup node %d not a leaf", l)
+		}
+		if !isOut[l] {
+			isOut[l] = true
+			nOut++
+		}
+	}
+	if nOut == 0 || nOut == nLeaves {
+		return graph.FromList{}, nil,
+			fmt.Errorf("outgroup has %d of %d leaves", nOut, nLeaves)
+	}
+	in := 0
+	for isOut[in] {
+		in++
+	}
+	// with the tree hanging from ingroup leaf in, find the node with
+	// exactly the outgroup leaves below it.
+	var x, y graph.NI = -1, -1
+	var xw float64
+	var f func(n, p graph.NI) (nl, no int)
+	f = func(n, p graph.NI) (nl, no int) {
+		if int(n) < nLeaves {
+			nl = 1
+			if isOut[n] {
+				no = 1
+			}
+		}
+		for _, h := range a[n] {
+			if h.To == p {
+				continue
+			}
+			cl, co := f(h.To, n)
+			if cl == nOut && co == nOut && x < 0 {
+				x, y, xw = h.To, n, wt[h.Label]
+			}
+			nl += cl
+			no += co
+		}
+		return
+	}
+	f(graph.NI(in), -1)
+	if x < 0 {
+		return graph.FromList{}, nil,
+			fmt.Errorf("no edge separates outgroup from other leaves")
+	}
+	pl, ul := rootTree(a, wt, nLeaves, x, y, .5*xw, .5*xw)
+	return pl, ul, nil
+}
+
+// rootTree builds the parent list rooted at node x if y < 0, otherwise at
+// a new node splitting the edge between x and y, with lengths lx and ly
+// from the new node.
+func rootTree(a graph.LabeledAdjacencyList, wt []float64, nLeaves int, x, y graph.NI, lx, ly float64) (graph.FromList, []Ultrametric) {
+	nn := len(a)
+	if y >= 0 {
+		nn++
+	}
+	pl := make([]graph.PathEnd, nn)
+	ul := make([]Ultrametric, nn)
+	var f func(n, p graph.NI, w float64)
+	f = func(n, p graph.NI, w float64) {
+		pl[n].From = p
+		ul[n].Weight = w
+		if int(n) < nLeaves {
+			pl[n].Len = 1
+			return
+		}
+		ul[n].Age = math.Inf(-1)
+		for _, h := range a[n] {
+			c := h.To
+			if c == p || n == x && c == y || n == y && c == x {
+				continue
+			}
+			f(c, n, wt[h.Label])
+			pl[n].Len += pl[c].Len
+			if age := ul[c].Age + ul[c].Weight; age > ul[n].Age {
+				ul[n].Age = age
+			}
+		}
+	}
+	if len(a) == 0 {
+		return graph.FromList{Paths: pl}, ul
+	}
+	r := x
+	if y >= 0 {
+		r = graph.NI(len(a))
+		f(x, r, lx)
+		f(y, r, ly)
+		pl[r].Len = pl[x].Len + pl[y].Len
+		ul[r].Age = math.Max(ul[x].Age+lx, ul[y].Age+ly)
+	} else {
+		f(r, -1, 0)
+	}
+	pl[r].From = -1
+	ul[r].Weight = math.NaN()
+	return graph.FromList{Paths: pl}, ul
+}
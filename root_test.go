@@ -0,0 +1,105 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+	"github.com/soniakeys/graph"
+)
+
+func ExampleRootMidpoint() {
+	d := cluster.DistanceMatrix{
+		{0, 13, 21, 22},
+		{13, 0, 12, 13},
+		{21, 12, 0, 13},
+		{22, 13, 13, 0},
+	}
+	u, wt := d.AdditiveTree()
+	pl, ul := cluster.RootMidpoint(u, wt, len(d))
+	cluster.WriteNewick(os.Stdout, pl, ul, nil)
+	// Output:
+	// (0:11,1:2,(2:6,3:7):4);
+}
+
+func ExampleRootOutgroup() {
+	d := cluster.DistanceMatrix{
+		{0, 13, 21, 22},
+		{13, 0, 12, 13},
+		{21, 12, 0, 13},
+		{22, 13, 13, 0},
+	}
+	u, wt := d.AdditiveTree()
+	pl, ul, err := cluster.RootOutgroup(u, wt, len(d), []graph.NI{2, 3})
+	if err != nil {
+		return
+	}
+	cluster.WriteNewick(os.Stdout, pl, ul, nil)
+	_, _, err = cluster.RootOutgroup(u, wt, len(d), []graph.NI{0, 3})
+	fmt.Println(err)
+	// Output:
+	// ((0:11,1:2):2,(2:6,3:7):2);
+	// no edge separates outgroup from other leaves
+}
+
+// rootedDist checks that path lengths of a rooted tree match d.
+func rootedDist(t *testing.T, dg *cluster.Dendrogram, d cluster.DistanceMatrix) {
+	t.Helper()
+	h := make([]float64, len(dg.Paths)) // path length from root
+	var f func(graph.NI)
+	f = func(n graph.NI) {
+		for _, c := range dg.Children(n) {
+			h[c] = h[n] + dg.Labels[c].Weight
+			f(c)
+		}
+	}
+	f(dg.Root)
+	for i := range d {
+		for j := range d[:i] {
+			a := dg.LCA(graph.NI(i), graph.NI(j))
+			if p := h[i] + h[j] - 2*h[a]; math.Abs(p-d[i][j]) > 1e-9 {
+				t.Fatalf("path %d-%d: %g != %g", i, j, p, d[i][j])
+			}
+		}
+	}
+}
+
+func TestRoot(t *testing.T) {
+	for _, n := range []int{3, 5, 20} {
+		d := cluster.RandomAdditiveMatrix(n)
+		u, wt := d.AdditiveTree()
+		dg := cluster.NewDendrogram(cluster.RootMidpoint(u, wt, n))
+		if dg.NLeaves != n || dg.Paths[dg.Root].Len != n {
+			t.Fatal("n =", n, "leaves", dg.NLeaves, dg.Paths[dg.Root].Len)
+		}
+		rootedDist(t, dg, d)
+		max := 0.
+		for _, di := range d {
+			for _, dij := range di {
+				max = math.Max(max, dij)
+			}
+		}
+		if math.Abs(dg.Labels[dg.Root].Age-max/2) > 1e-9 {
+			t.Fatal("midpoint root age", dg.Labels[dg.Root].Age, "diameter", max)
+		}
+		for _, c := range dg.Children(dg.Root) {
+			pl, ul, err := cluster.RootOutgroup(u, wt, n, dg.Leaves(c))
+			if err != nil {
+				t.Fatal(err)
+			}
+			og := cluster.NewDendrogram(pl, ul)
+			rootedDist(t, og, d)
+		}
+		pl, ul, err := cluster.RootOutgroup(u, wt, n,
+			[]graph.NI{graph.NI(rand.Intn(n))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rootedDist(t, cluster.NewDendrogram(pl, ul), d)
+	}
+}
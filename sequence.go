@@ -0,0 +1,430 @@
+// Public domain.
+
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ReadFasta reads aligned sequences in FASTA format.
+//
+// Each sequence follows a header line starting with '>'.  The name is the
+// first whitespace delimited field of the header.  Sequence lines are
+// concatenated with whitespace removed.  Blank lines and lines starting with
+// ';' are ignored.  All sequences must be the same length.
+//
+// Errors are returned as *ParseError.
+func ReadFasta(r io.Reader) (seqs, names []string, err error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<26)
+	line := 0
+	var b strings.Builder
+	seqLine := 0 // header line of current sequence
+	end := func() error {
+		if len(names) == 0 {
+			return nil
+		}
+		s := b.String()
+		b.Reset()
+		if len(seqs) > 0 && len(s) != len(seqs[0]) {
+			return &ParseError{"fasta", seqLine, fmt.Sprintf(
+				"sequence %q has length %d, expected %d",
+				names[len(seqs)], len(s), len(seqs[0]))}
+		}
+		seqs = append(seqs, s)
+		return nil
+	}
+	for sc.Scan() {
+		line++
+		t := strings.TrimSpace(sc.Text())
+		switch {
+		case t == "" || t[0] == ';':
+		case t[0] == '>':
+			if err = end(); err != nil {
+				return nil, nil, err
+			}
+			f := strings.Fields(t[1:])
+			if len(f) == 0 {
+				return nil, nil, &ParseError{"fasta", line, "missing name"}
+			}
+			names = append(names, f[0])
+			seqLine = line
+		case len(names) == 0:
+			return nil, nil, &ParseError{"fasta", line, "expected '>'"}
+		default:
+			for _, f := range strings.Fields(t) {
+				b.WriteString(f)
+			}
+		}
+	}
+	if err = sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	if err = end(); err != nil {
+		return nil, nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil, &ParseError{"fasta", line, "no sequences"}
+	}
+	return seqs, names, nil
+}
+
+// ReadPhylipAlignment reads aligned sequences in PHYLIP format.
+//
+// The first line holds the number of sequences n and the number of sites.
+// Each sequence starts with its name, read according to mode as for
+// ReadPhylipDist.  If interleaved is false, each sequence is complete before
+// the next begins and may continue on following lines.  If interleaved is
+// true, the first n lines hold names and the first part of each sequence
+// and following blocks of n lines continue the sequences in order.
+// Whitespace within sequences is ignored, as are blank lines.
+//
+// Errors are returned as *ParseError.
+func ReadPhylipAlignment(r io.Reader, mode int, interleaved bool) (seqs, names []string, err error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<26)
+	line := 0
+	next := func() (string, bool) {
+		for sc.Scan() {
+			line++
+			if t := sc.Text(); strings.TrimSpace(t) != "" {
+				return t, true
+			}
+		}
+		return "", false
+	}
+	errorf := func(format string, a ...interface{}) error {
+		if err := sc.Err(); err != nil {
+			return err
+		}
+		return &ParseError{"phylip", line, fmt.Sprintf(format, a...)}
+	}
+	t, ok := next()
+	if !ok {
+		return nil, nil, errorf("no data")
+	}
+	f := strings.Fields(t)
+	if len(f) < 2 {
+		return nil, nil, errorf("expected number of sequences and sites")
+	}
+	n, err1 := strconv.Atoi(f[0])
+	m, err2 := strconv.Atoi(f[1])
+	if err1 != nil || err2 != nil || n < 0 || m < 0 {
+		return nil, nil, errorf("invalid header %q", strings.TrimSpace(t))
+	}
+	sb := make([]strings.Builder, n)
+	names = make([]string, n)
+	add := func(i int, s string) error {
+		for _, f := range strings.Fields(s) {
+			sb[i].WriteString(f)
+		}
+		if sb[i].Len() > m {
+			return errorf("sequence %q has more than %d sites", names[i], m)
+		}
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		if t, ok = next(); !ok {
+			return nil, nil, errorf("unexpected end of input, "+
+				"expected sequence %d of %d", i+1, n)
+		}
+		var rest string
+		if mode == PhylipStrict {
+			if len(t) > 10 {
+				names[i], rest = strings.TrimRight(t[:10], " \t"), t[10:]
+			} else {
+				names[i] = strings.TrimRight(t, " \t")
+			}
+		} else {
+			t = strings.TrimLeft(t, " \t")
+			x := strings.IndexAny(t, " \t")
+			if x < 0 {
+				x = len(t)
+			}
+			names[i], rest = t[:x], t[x:]
+		}
+		if err = add(i, rest); err != nil {
+			return nil, nil, err
+		}
+		for !interleaved && sb[i].Len() < m {
+			if t, ok = next(); !ok {
+				return nil, nil, errorf("unexpected end of input, "+
+					"sequence %q has %d sites, expected %d",
+					names[i], sb[i].Len(), m)
+			}
+			if err = add(i, t); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for interleaved && n > 0 && sb[n-1].Len() < m {
+		for i := range sb {
+			if t, ok = next(); !ok {
+				return nil, nil, errorf("unexpected end of input, "+
+					"sequence %q has %d sites, expected %d",
+					names[i], sb[i].Len(), m)
+			}
+			if err = add(i, t); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	seqs = make([]string, n)
+	for i := range sb {
+		if sb[i].Len() != m {
+			return nil, nil, errorf("sequence %q has %d sites, expected %d",
+				names[i], sb[i].Len(), m)
+		}
+		seqs[i] = sb[i].String()
+	}
+	return seqs, names, nil
+}
+
+// SeqDNA, SeqProtein constants for the alphabet argument of NewSeqDist.
+const (
+	SeqDNA     = iota // nucleotides ACGT, U read as T
+	SeqProtein        // the 20 standard amino acids
+)
+
+// SeqP, SeqJC, SeqK2P, SeqTN, SeqLogDet constants for the model argument
+// of NewSeqDist.
+const (
+	SeqP      = iota // p-distance, the proportion of differing sites
+	SeqJC            // Jukes-Cantor
+	SeqK2P           // Kimura 2-parameter, DNA only
+	SeqTN            // Tamura-Nei, DNA only
+	SeqLogDet        // LogDet
+)
+
+// PairwiseDeletion, CompleteDeletion constants for the deletion argument of
+// NewSeqDist.
+const (
+	PairwiseDeletion = iota // skip sites missing in either sequence of a pair
+	CompleteDeletion        // skip sites missing in any sequence
+)
+
+// NewSeqDist constructs an n×n distance matrix where n is len(seqs) using
+// an evolutionary model of sequence change.
+//
+// Sequences must be aligned, of equal length.  Characters are case
+// insensitive.  Characters other than the states of the alphabet, such as
+// gaps, ambiguity codes like N or X, and '?', are missing data.  With
+// PairwiseDeletion, the distance between two sequences is computed from the
+// sites where neither is missing.  With CompleteDeletion, only sites with no
+// missing data in any sequence are used.
+//
+// Models are:
+//
+//   - SeqP:  p, the proportion of sites that differ.
+//   - SeqJC:  -b ln(1 - p/b), where b = 3/4 for DNA and 19/20 for protein.
+//   - SeqK2P:  -ln(1 - 2P - Q)/2 - ln(1 - 2Q)/4, where P and Q are the
+//     proportions of transitions and transversions.
+//   - SeqTN:  the distance of Tamura and Nei, 1993, with base frequencies
+//     taken from all sequences over the sites used.
+//   - SeqLogDet:  -(ln det F - (ln det Fx + ln det Fy)/2)/k, where F is the
+//     k×k matrix of proportions of sites with each pair of states and Fx
+//     and Fy are diagonal matrices of state proportions of each sequence.
+//
+// An error is returned if sequences differ in length, if a model is not
+// defined for the alphabet, if two sequences have no sites in common, or if
+// a distance is undefined, as when the sequences are too different for the
+// model.
+func NewSeqDist(seqs []string, alphabet, model, deletion int) (DistanceMatrix, error) {
+	var states string
+	switch alphabet {
+	case SeqDNA:
+		states = "ACGT"
+	case SeqProtein:
+		states = "ACDEFGHIKLMNPQRSTVWY"
+	default:
+		return nil, fmt.Errorf("invalid alphabet %d", alphabet)
+	}
+	if alphabet != SeqDNA && (model == SeqK2P || model == SeqTN) {
+		return nil, fmt.Errorf("model %d requires DNA", model)
+	}
+	if model < SeqP || model > SeqLogDet {
+		return nil, fmt.Errorf("invalid model %d", model)
+	}
+	var code [256]int8 // state index, -1 for missing
+	for i := range code {
+		code[i] = -1
+	}
+	for i := 0; i < len(states); i++ {
+		code[states[i]] = int8(i)
+		code[states[i]+'a'-'A'] = int8(i)
+	}
+	if alphabet == SeqDNA {
+		code['U'], code['u'] = 3, 3
+	}
+	k := len(states)
+	// encode, dropping columns for complete deletion
+	x := make([][]int8, len(seqs))
+	for i, s := range seqs {
+		if len(s) != len(seqs[0]) {
+			return nil, fmt.Errorf("sequence %d has length %d, expected %d",
+				i, len(s), len(seqs[0]))
+		}
+		xi := make([]int8, len(s))
+		for j := 0; j < len(s); j++ {
+			xi[j] = code[s[j]]
+		}
+		x[i] = xi
+	}
+	if deletion == CompleteDeletion && len(x) > 0 {
+		keep := 0
+	col:
+		for j := range x[0] {
+			for _, xi := range x {
+				if xi[j] < 0 {
+					continue col
+				}
+			}
+			for _, xi := range x {
+				xi[keep] = xi[j]
+			}
+			keep++
+		}
+		for i := range x {
+			x[i] = x[i][:keep]
+		}
+	}
+	// overall state frequencies for Tamura-Nei
+	freq := make([]float64, k)
+	if model == SeqTN {
+		t := 0.
+		for _, xi := range x {
+			for _, c := range xi {
+				if c >= 0 {
+					freq[c]++
+					t++
+				}
+			}
+		}
+		for c := range freq {
+			freq[c] /= t
+		}
+	}
+	dm := make(DistanceMatrix, len(x))
+	for i := range dm {
+		dm[i] = make([]float64, len(x))
+	}
+	f := make([][]float64, k) // pair counts
+	for a := range f {
+		f[a] = make([]float64, k)
+	}
+	for i, xi := range x {
+		for j, xj := range x[:i] {
+			for _, fa := range f {
+				for b := range fa {
+					fa[b] = 0
+				}
+			}
+			n := 0.
+			for s, a := range xi {
+				if b := xj[s]; a >= 0 && b >= 0 {
+					f[a][b]++
+					n++
+				}
+			}
+			if n == 0 {
+				return nil, fmt.Errorf("sequences %d and %d have no sites in common", j, i)
+			}
+			for _, fa := range f {
+				for b := range fa {
+					fa[b] /= n
+				}
+			}
+			d := seqModelDist(f, model, freq)
+			if math.IsNaN(d) || math.IsInf(d, 0) {
+				return nil, fmt.Errorf("distance between sequences %d and %d "+
+					"undefined for model", j, i)
+			}
+			dm[i][j] = d
+			dm[j][i] = d
+		}
+	}
+	return dm, nil
+}
+
+// seqModelDist computes the distance for a model from the matrix f of
+// proportions of sites with each pair of states.  It returns NaN or Inf
+// where the distance is undefined.
+func seqModelDist(f [][]float64, model int, freq []float64) float64 {
+	k := len(f)
+	p := 1.
+	for a := range f {
+		p -= f[a][a]
+	}
+	switch model {
+	case SeqP:
+		return p
+	case SeqJC:
+		b := float64(k-1) / float64(k)
+		return -b * math.Log1p(-p/b)
+	case SeqK2P, SeqTN:
+		// states A C G T: transitions A<->G, C<->T
+		p1 := f[0][2] + f[2][0]
+		p2 := f[1][3] + f[3][1]
+		q := p - p1 - p2
+		if model == SeqK2P {
+			pt := p1 + p2
+			return -.5*math.Log1p(-2*pt-q) - .25*math.Log1p(-2*q)
+		}
+		ga, gc, gg, gt := freq[0], freq[1], freq[2], freq[3]
+		gr, gy := ga+gg, gc+gt
+		return -2*ga*gg/gr*math.Log1p(-gr*p1/(2*ga*gg)-q/(2*gr)) -
+			2*gc*gt/gy*math.Log1p(-gy*p2/(2*gc*gt)-q/(2*gy)) -
+			2*(gr*gy-ga*gg*gy/gr-gc*gt*gr/gy)*math.Log1p(-q/(2*gr*gy))
+	}
+	// LogDet
+	ld := 0. // ln det Fx + ln det Fy
+	for a := range f {
+		fx, fy := 0., 0.
+		for b := range f {
+			fx += f[a][b]
+			fy += f[b][a]
+		}
+		ld += math.Log(fx) + math.Log(fy)
+	}
+	det := luDet(f)
+	if !(det > 0) {
+		return math.NaN()
+	}
+	return (.5*ld - math.Log(det)) / float64(k)
+}
+
+// luDet returns the determinant of square matrix a by LU decomposition with
+// partial pivoting.  Argument a is destroyed.
+func luDet(a [][]float64) float64 {
+	det := 1.
+	for c := range a {
+		p := c
+		for r := c + 1; r < len(a); r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if a[p][c] == 0 {
+			return 0
+		}
+		if p != c {
+			a[p], a[c] = a[c], a[p]
+			det = -det
+		}
+		ac := a[c]
+		det *= ac[c]
+		for r := c + 1; r < len(a); r++ {
+			ar := a[r]
+			m := ar[c] / ac[c]
+			for j := c; j < len(a); j++ {
+				ar[j] -= m * ac[j]
+			}
+		}
+	}
+	return det
+}
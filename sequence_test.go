@@ -0,0 +1,129 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleNewSeqDist() {
+	seqs, names, err := cluster.ReadFasta(strings.NewReader(`>human
+ACGTACGTAC
+GTACGTACGT
+>chimp
+ACGTACGTAC
+GTACGTATGT
+>gorilla
+ACGTAC-TAC
+GTACNTATGA
+`))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(names)
+	for _, del := range []int{cluster.PairwiseDeletion, cluster.CompleteDeletion} {
+		d, err := cluster.NewSeqDist(seqs, cluster.SeqDNA, cluster.SeqP, del)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("%.4f\n", d)
+	}
+	d, _ := cluster.NewSeqDist(seqs, cluster.SeqDNA, cluster.SeqK2P,
+		cluster.PairwiseDeletion)
+	fmt.Printf("%.4f\n", d)
+	// Output:
+	// [human chimp gorilla]
+	// [[0.0000 0.0500 0.1111] [0.0500 0.0000 0.0556] [0.1111 0.0556 0.0000]]
+	// [[0.0000 0.0556 0.1111] [0.0556 0.0000 0.0556] [0.1111 0.0556 0.0000]]
+	// [[0.0000 0.0527 0.1206] [0.0527 0.0000 0.0580] [0.1206 0.0580 0.0000]]
+}
+
+func ExampleReadPhylipAlignment() {
+	seqs, names, err := cluster.ReadPhylipAlignment(strings.NewReader(`3 12
+human     ACGTAC
+chimp     ACGTAC
+gorilla   ACGTAC
+
+GTACGT
+GTACTT
+GAACTT
+`), cluster.PhylipStrict, true)
+	fmt.Println(err)
+	for i, s := range seqs {
+		fmt.Println(names[i], s)
+	}
+	// Output:
+	// <nil>
+	// human ACGTACGTACGT
+	// chimp ACGTACGTACTT
+	// gorilla ACGTACGAACTT
+}
+
+func TestReadPhylipAlignmentSequential(t *testing.T) {
+	seqs, names, err := cluster.ReadPhylipAlignment(strings.NewReader(`2 8
+a ACGT
+AC GT
+bb ACGTTTTT
+`), cluster.PhylipRelaxed, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(names, seqs) != "[a bb] [ACGTACGT ACGTTTTT]" {
+		t.Fatal(names, seqs)
+	}
+	_, _, err = cluster.ReadPhylipAlignment(strings.NewReader("2 8\na ACGT\n"),
+		cluster.PhylipRelaxed, false)
+	if _, ok := err.(*cluster.ParseError); !ok {
+		t.Fatal("expected ParseError, got", err)
+	}
+}
+
+func TestSeqDist(t *testing.T) {
+	// equal base frequencies and equal A-G and C-T transitions:
+	// Tamura-Nei reduces to Kimura 2-parameter.
+	seqs := []string{
+		"AAAACCCCGGGGTTTTAAAACCCCGGGGTTTT",
+		"GAAATCCCAGGGCTTTCAAAACCCGGGGTTTT",
+	}
+	k2p, err := cluster.NewSeqDist(seqs, cluster.SeqDNA, cluster.SeqK2P,
+		cluster.PairwiseDeletion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tn, err := cluster.NewSeqDist(seqs, cluster.SeqDNA, cluster.SeqTN,
+		cluster.PairwiseDeletion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(k2p[0][1]-tn[0][1]) > 1e-12 {
+		t.Fatal("K2P", k2p[0][1], "TN", tn[0][1])
+	}
+	// LogDet of identical sequences is 0
+	ld, err := cluster.NewSeqDist([]string{seqs[0], seqs[0]}, cluster.SeqDNA,
+		cluster.SeqLogDet, cluster.PairwiseDeletion)
+	if err != nil || math.Abs(ld[0][1]) > 1e-12 {
+		t.Fatal("LogDet", ld, err)
+	}
+	// JC on protein
+	jc, err := cluster.NewSeqDist([]string{"MKV-LA", "MKVXLS"},
+		cluster.SeqProtein, cluster.SeqJC, cluster.PairwiseDeletion)
+	if want := -.95 * math.Log(1-.2/.95); err != nil ||
+		math.Abs(jc[0][1]-want) > 1e-12 {
+		t.Fatal("protein JC", jc, err, "want", want)
+	}
+	if _, err = cluster.NewSeqDist(seqs, cluster.SeqProtein, cluster.SeqK2P,
+		cluster.PairwiseDeletion); err == nil {
+		t.Fatal("K2P accepted protein")
+	}
+	if _, err = cluster.NewSeqDist([]string{"ACGT", "TGCA"}, cluster.SeqDNA,
+		cluster.SeqJC, cluster.PairwiseDeletion); err == nil {
+		t.Fatal("saturated JC accepted")
+	}
+}
@@ -0,0 +1,295 @@
+// Public domain.
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/soniakeys/bits"
+	"github.com/soniakeys/graph"
+)
+
+// Split is a set of leaves separated from the rest of a tree by an edge.
+//
+// For an unrooted tree a Split represents a bipartition of the leaves by
+// one of its sides.  For a rooted tree it represents the cluster of leaves
+// under a node.  Weight is the branch length of the edge.
+type Split struct {
+	Leaves bits.Bits // Leaves.Num is the number of leaves of the tree
+	Weight float64
+}
+
+// Trivial returns true if the split, as a bipartition of an unrooted tree,
+// separates a single leaf or no leaves from the rest of the tree.
+//
+// Trivial splits are common to all trees on the same leaves and so do not
+// contribute to the Robinson-Foulds distance.  For clusters of a rooted
+// tree, use TrivialRooted.
+func (s Split) Trivial() bool {
+	c := s.Leaves.OnesCount()
+	return c <= 1 || c >= s.Leaves.Num-1
+}
+
+// TrivialRooted returns true if the split, as a cluster of a rooted tree,
+// holds a single leaf, no leaves, or all leaves.
+//
+// Unlike for bipartitions, a cluster of all leaves but one is not trivial.
+func (s Split) TrivialRooted() bool {
+	c := s.Leaves.OnesCount()
+	return c <= 1 || c >= s.Leaves.Num
+}
+
+// Bipartitions returns the splits of an unrooted tree.
+//
+// The tree is given as an undirected graph with edge labels indexing the
+// weight list wt, as returned by NeighborJoin, AdditiveTree, or ReadNewick.
+// Leaves must be nodes 0:nLeaves.
+//
+// There is a split for each edge, including trivial splits of pendant
+// edges.  Each split is given by the side not containing leaf 0.  Edges
+// giving the same split, as from a node of degree two, are combined into a
+// single split with the sum of their weights.  Splits are returned in
+// depth-first order from leaf 0.
+func Bipartitions(u graph.LabeledUndirected, wt []float64, nLeaves int) []Split {
+	var s []Split
+	x := map[string]int{}
+	edgeSplits(u.LabeledAdjacencyList, nLeaves, func(l graph.LI, b bits.Bits) {
+		k := b.String()
+		if i, ok := x[k]; ok {
+			s[i].Weight += wt[l]
+		} else {
+			x[k] = len(s)
+			s = append(s, Split{b, wt[l]})
+		}
+	})
+	return s
+}
+
+// edgeSplits calls visit with the label of each edge of an unrooted tree and
+// the leaves on the side of the edge not containing leaf 0, in depth-first
+// order from leaf 0.
+func edgeSplits(a graph.LabeledAdjacencyList, nLeaves int, visit func(graph.LI, bits.Bits)) {
+	if nLeaves == 0 {
+		return
+	}
+	var f func(n, p graph.NI) bits.Bits
+	f = func(n, p graph.NI) bits.Bits {
+		b := bits.New(nLeaves)
+		if int(n) < nLeaves {
+			b.SetBit(int(n), 1)
+		}
+		for _, h := range a[n] {
+			if h.To != p {
+				c := f(h.To, n)
+				visit(h.Label, c)
+				b.Or(b, c)
+			}
+		}
+		return b
+	}
+	f(0, -1)
+}
+
+// Bipartitions returns the splits of t.
+//
+// See the function Bipartitions.  Use RelabelSplits with t.Names to compare
+// with trees on differently ordered leaves.
+func (t LabeledTree) Bipartitions() []Split {
+	return Bipartitions(t.LabeledUndirected, t.Weights, len(t.Names))
+}
+
+// Clusters returns the splits of a rooted tree.
+//
+// The tree is given as a parent list and labels as returned by
+// DistanceMatrix.Ultrametric or RootMidpoint.  Leaves must be nodes
+// 0:nLeaves.
+//
+// There is a split for each node other than the root, giving the leaves
+// under the node and the weight of the edge to its parent.  Splits for the
+// leaves themselves are included.  Splits are returned in order of node
+// number.  Unlike Bipartitions, the two children of a root give separate
+// splits.
+func Clusters(pl graph.FromList, ul []Ultrametric, nLeaves int) []Split {
+	lv := nodeLeaves(pl, nLeaves)
+	var s []Split
+	for n, pn := range pl.Paths {
+		if pn.From >= 0 {
+			s = append(s, Split{lv[n], ul[n].Weight})
+		}
+	}
+	return s
+}
+
+// Clusters returns the splits of dg.
+//
+// See the function Clusters.
+func (dg *Dendrogram) Clusters() []Split {
+	return Clusters(dg.FromList, dg.Labels, dg.NLeaves)
+}
+
+// nodeLeaves returns the set of leaves under each node of a rooted tree.
+func nodeLeaves(pl graph.FromList, nLeaves int) []bits.Bits {
+	p := pl.Paths
+	lv := make([]bits.Bits, len(p))
+	for n := range p {
+		lv[n] = bits.New(nLeaves)
+	}
+	for l := 0; l < nLeaves; l++ {
+		for n := graph.NI(l); n >= 0; n = p[n].From {
+			lv[n].SetBit(l, 1)
+		}
+	}
+	return lv
+}
+
+// Unroot returns the bipartitions corresponding to the clusters of a rooted
+// tree.
+//
+// Each split is replaced by its complement if it contains leaf 0, so that
+// splits are given in the form returned by Bipartitions.  Splits that become
+// the same, as the two sides of a root of degree two, are combined with the
+// sum of their weights.  Use Unroot to compare a rooted tree with an
+// unrooted one.
+func Unroot(s []Split) []Split {
+	var r []Split
+	x := map[string]int{}
+	for _, sp := range s {
+		b := sp.Leaves
+		if b.Num > 0 && b.Bit(0) == 1 {
+			c := bits.New(b.Num)
+			c.Not(b)
+			b = c
+		}
+		k := b.String()
+		if i, ok := x[k]; ok {
+			r[i].Weight += sp.Weight
+		} else {
+			x[k] = len(r)
+			r = append(r, Split{b, sp.Weight})
+		}
+	}
+	return r
+}
+
+// RelabelSplits renumbers the leaves of splits by name.
+//
+// Leaf n of the splits s has name names[n].  In the result it is numbered
+// by the position of that name in order.  Use RelabelSplits to compare
+// trees built from matrices with differently ordered names.  Names must be
+// unique and order must be a permutation of names, otherwise an error is
+// returned.  As leaf 0 may change, relabeled bipartitions should be passed
+// through Unroot to restore the form returned by Bipartitions.
+func RelabelSplits(s []Split, names, order []string) ([]Split, error) {
+	if len(names) != len(order) {
+		return nil, fmt.Errorf("%d names, %d in order", len(names), len(order))
+	}
+	x, err := nameIndexes(order, names)
+	if err != nil {
+		return nil, err
+	}
+	seen := make([]bool, len(order))
+	for _, i := range x {
+		if seen[i] {
+			return nil, fmt.Errorf("duplicate name %q", order[i])
+		}
+		seen[i] = true
+	}
+	r := make([]Split, len(s))
+	for i, sp := range s {
+		b := bits.New(len(order))
+		sp.Leaves.IterateOnes(func(n int) bool {
+			b.SetBit(x[n], 1)
+			return true
+		})
+		r[i] = Split{b, sp.Weight}
+	}
+	return r, nil
+}
+
+// RobinsonFoulds returns the Robinson-Foulds distance between two trees
+// given by their splits.
+//
+// The distance is the number of splits in one set but not the other.
+// Splits are compared by leaves only.  Splits should be of trees on the
+// same leaves, as returned by Bipartitions or Clusters.
+func RobinsonFoulds(s1, s2 []Split) int {
+	m := splitMap(s1)
+	rf := 0
+	for _, s := range s2 {
+		k := s.Leaves.String()
+		if _, ok := m[k]; ok {
+			delete(m, k)
+		} else {
+			rf++
+		}
+	}
+	return rf + len(m)
+}
+
+// RobinsonFouldsNorm returns the Robinson-Foulds distance divided by the
+// number of non-trivial splits in both sets.
+//
+// If rooted is true, splits are taken as clusters of rooted trees, as
+// returned by Clusters, and triviality is determined by TrivialRooted.
+// Otherwise they are taken as bipartitions of unrooted trees, as returned
+// by Bipartitions, and triviality is determined by Trivial.
+//
+// The result ranges from 0 for trees with the same splits to 1 for trees
+// with no non-trivial splits in common.  For two trees with no non-trivial
+// splits the result is 0.
+func RobinsonFouldsNorm(s1, s2 []Split, rooted bool) float64 {
+	trivial := Split.Trivial
+	if rooted {
+		trivial = Split.TrivialRooted
+	}
+	n := 0
+	for _, s := range s1 {
+		if !trivial(s) {
+			n++
+		}
+	}
+	for _, s := range s2 {
+		if !trivial(s) {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(RobinsonFoulds(s1, s2)) / float64(n)
+}
+
+// BranchScore returns the branch score distance of Kuhner and Felsenstein
+// between two trees given by their splits.
+//
+// The distance is the square root of the sum over all splits of the squared
+// difference in weight, where a split missing from one set counts as
+// weight 0.  Trivial splits are included so that pendant edge lengths are
+// compared.
+func BranchScore(s1, s2 []Split) float64 {
+	m := splitMap(s1)
+	sum := 0.
+	for _, s := range s2 {
+		k := s.Leaves.String()
+		w := s.Weight
+		if w1, ok := m[k]; ok {
+			w -= w1
+			delete(m, k)
+		}
+		sum += w * w
+	}
+	for _, w := range m {
+		sum += w * w
+	}
+	return math.Sqrt(sum)
+}
+
+// splitMap maps split leaf sets to weights.
+func splitMap(s []Split) map[string]float64 {
+	m := make(map[string]float64, len(s))
+	for _, sp := range s {
+		m[sp.Leaves.String()] += sp.Weight
+	}
+	return m
+}
@@ -0,0 +1,109 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleRobinsonFoulds() {
+	t1, _ := cluster.ReadNewickTree(strings.NewReader(
+		"((a:1,b:1):1,c:1,(d:1,e:1):1);"))
+	t2, _ := cluster.ReadNewickTree(strings.NewReader(
+		"((a:1,c:1):1,b:1,(d:1,e:2):1);"))
+	s1 := t1.Bipartitions()
+	s2, err := cluster.RelabelSplits(t2.Bipartitions(), t2.Names, t1.Names)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	s2 = cluster.Unroot(s2)
+	for _, s := range s1 {
+		if !s.Trivial() {
+			fmt.Println(s.Leaves.Slice(), s.Weight)
+		}
+	}
+	fmt.Println(cluster.RobinsonFoulds(s1, s2))
+	fmt.Println(cluster.RobinsonFouldsNorm(s1, s2, false))
+	fmt.Printf("%.4f\n", cluster.BranchScore(s1, s2))
+	// Output:
+	// [3 4] 1
+	// [2 3 4] 1
+	// 2
+	// 0.5
+	// 1.7321
+}
+
+func TestUnroot(t *testing.T) {
+	for _, n := range []int{3, 5, 20} {
+		d := cluster.RandomAdditiveMatrix(n)
+		u, wt := d.AdditiveTree()
+		b := cluster.Bipartitions(u, wt, n)
+		c := cluster.Unroot(cluster.NewDendrogram(cluster.RootMidpoint(u, wt, n)).Clusters())
+		if rf := cluster.RobinsonFoulds(b, c); rf != 0 {
+			t.Fatal("n =", n, "RF", rf)
+		}
+		if bs := cluster.BranchScore(b, c); bs > 1e-9 {
+			t.Fatal("n =", n, "branch score", bs)
+		}
+		if len(b) != len(c) {
+			t.Fatal("n =", n, len(b), "bipartitions", len(c), "unrooted")
+		}
+	}
+}
+
+func TestRobinsonFouldsNormRooted(t *testing.T) {
+	// ((a,b),c),d and ((a,c),b),d share cluster a,b,c of n-1 leaves
+	d1 := cluster.DistanceMatrix{
+		{0, 2, 4, 6},
+		{2, 0, 4, 6},
+		{4, 4, 0, 6},
+		{6, 6, 6, 0},
+	}
+	d2 := cluster.DistanceMatrix{
+		{0, 4, 2, 6},
+		{4, 0, 4, 6},
+		{2, 4, 0, 6},
+		{6, 6, 6, 0},
+	}
+	pl1, ul1 := d1.Ultrametric(cluster.DAVG)
+	pl2, ul2 := d2.Ultrametric(cluster.DAVG)
+	c1 := cluster.Clusters(pl1, ul1, 4)
+	c2 := cluster.Clusters(pl2, ul2, 4)
+	if rf := cluster.RobinsonFoulds(c1, c2); rf != 2 {
+		t.Fatal("RF", rf)
+	}
+	if rf := cluster.RobinsonFouldsNorm(c1, c2, true); rf != .5 {
+		t.Fatal("normalized RF", rf)
+	}
+	// (((b,c),d),a) and (((b,d),c),a), filtered to non-trivial clusters,
+	// none of which contain leaf 0
+	nonTrivial := func(d cluster.DistanceMatrix) (s []cluster.Split) {
+		pl, ul := d.Ultrametric(cluster.DAVG)
+		for _, sp := range cluster.Clusters(pl, ul, len(d)) {
+			if !sp.TrivialRooted() {
+				s = append(s, sp)
+			}
+		}
+		return
+	}
+	c1 = nonTrivial(cluster.DistanceMatrix{
+		{0, 8, 8, 8},
+		{8, 0, 2, 4},
+		{8, 2, 0, 4},
+		{8, 4, 4, 0},
+	})
+	c2 = nonTrivial(cluster.DistanceMatrix{
+		{0, 8, 8, 8},
+		{8, 0, 4, 2},
+		{8, 4, 0, 4},
+		{8, 2, 4, 0},
+	})
+	if rf := cluster.RobinsonFouldsNorm(c1, c2, true); rf != .5 {
+		t.Fatal("normalized RF, filtered", rf)
+	}
+}
@@ -3,8 +3,6 @@
 
 package cluster
 
-import "math/rand"
-
 // bleh.  this code started out in the bio package, then got moved to the graph
 // package, but it's too quirky and special purporse for graph.  moved here
 // now as a non-exported function.
@@ -72,7 +70,9 @@ import "math/rand"
 //        /
 //       4
 //
-func randomUTree(nLeaves int) (parentList []int) {
+// Random numbers are taken from intn, as rand.Intn or a method value of a
+// rand.Rand.
+func randomUTree(nLeaves int, intn func(int) int) (parentList []int) {
 	// allocate space for whole tree except root
 	parentList = make([]int, nLeaves+nLeaves-3)
 	// initial tree has three leaves and the internal root
@@ -86,8 +86,8 @@ func randomUTree(nLeaves int) (parentList []int) {
 	// new edges are from new leaf to new internal node and from
 	// new internal node to parent.
 	for newLeaf := 3; newLeaf < nLeaves; newLeaf++ {
-		i := nLeaves + newLeaf - 3     // new internal node
-		l1 := rand.Intn(newLeaf*2 - 3) // (range is number of existing edges)
+		i := nLeaves + newLeaf - 3 // new internal node
+		l1 := intn(newLeaf*2 - 3)  // (range is number of existing edges)
 		if l1 >= newLeaf {
 			l1 += nLeaves - newLeaf // skip to range of internal nodes
 		}
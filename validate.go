@@ -0,0 +1,302 @@
+// Public domain.
+
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// MaxViolations is the maximum number of violations listed in a
+// ValidationReport.  Counts in the report include all violations.
+const MaxViolations = 1000
+
+// Violation describes one failure of a distance matrix condition.
+//
+// The embedded MatrixError gives the condition and indexes locating the
+// failure.  Size is the amount by which the condition fails:
+//
+//   - CondSquare:  |len(d[i]) - len(d)|
+//   - CondNotNaN:  NaN
+//   - CondNonNegative:  -d[i][j]
+//   - CondSymmetric:  |d[i][j] - d[j][i]|
+//   - CondZeroDiagonal:  |d[i][i]|
+//   - CondTriangle:  d[i][k] - d[i][j] - d[j][k]
+//   - CondAdditive:  the largest of the three four-point sums minus the
+//     second largest.
+//   - CondUltrametric:  the largest of d[i][j], d[i][k], and d[j][k] minus
+//     the second largest.
+type Violation struct {
+	MatrixError
+	Size float64
+}
+
+// ValidationReport collects violations of distance matrix conditions.
+//
+// Violations lists violations in the order found, up to MaxViolations.
+// Count and MaxSize summarize all violations found, indexed by condition.
+type ValidationReport struct {
+	Eps        float64 // tolerance used
+	Violations []Violation
+	Count      [nCond]int
+	MaxSize    [nCond]float64
+}
+
+// Total returns the total number of violations found.
+func (r *ValidationReport) Total() int {
+	t := 0
+	for _, c := range r.Count {
+		t += c
+	}
+	return t
+}
+
+// OK returns true if no violations were found.
+func (r *ValidationReport) OK() bool {
+	return r.Total() == 0
+}
+
+// String returns a summary of the report, a line for each condition with
+// violations giving the count and the largest size.
+func (r *ValidationReport) String() string {
+	if r.OK() {
+		return "no violations"
+	}
+	var b bytes.Buffer
+	for c, n := range r.Count {
+		if n == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s: %d, max %g", condNames[c], n, r.MaxSize[c])
+	}
+	return b.String()
+}
+
+var condNames = [...]string{
+	CondSquare:       "not square",
+	CondNotNaN:       "NaN",
+	CondNonNegative:  "negative",
+	CondSymmetric:    "not symmetric",
+	CondZeroDiagonal: "non-zero diagonal",
+	CondTriangle:     "triangle inequality",
+	CondAdditive:     "not additive",
+	CondUltrametric:  "not ultrametric",
+	CondSize:         "too few rows",
+	CondFinite:       "infinite",
+}
+
+// add records a violation.
+func (r *ValidationReport) add(cond int, size float64, x ...int) {
+	if len(r.Violations) < MaxViolations {
+		r.Violations = append(r.Violations, Violation{MatrixError{cond, x}, size})
+	}
+	r.Count[cond]++
+	if r.Count[cond] == 1 || size > r.MaxSize[cond] || math.IsNaN(size) {
+		r.MaxSize[cond] = size
+	}
+}
+
+// ValidationError is returned by Validate for a matrix with violations.
+//
+// Unwrap returns the first violation as a *MatrixError.
+type ValidationError struct {
+	Report *ValidationReport
+}
+
+func (e *ValidationError) Error() string {
+	v := &e.Report.Violations[0].MatrixError
+	if n := e.Report.Total() - 1; n > 0 {
+		return fmt.Sprintf("%v (and %d more violations)", v, n)
+	}
+	return v.Error()
+}
+
+// Unwrap returns the first violation of the report, so that errors.As can
+// find a *MatrixError.
+func (e *ValidationError) Unwrap() error {
+	return &e.Report.Violations[0].MatrixError
+}
+
+// ValidateEps checks the conditions of Validate with tolerance eps,
+// collecting all violations.
+//
+// Elements may be negative by up to eps, differ from their transposes by up
+// to eps, and diagonal elements may differ from zero by up to eps.  The
+// triangle inequality may fail by up to eps.  If d is not square, only the
+// square condition is reported.
+func (d DistanceMatrix) ValidateEps(eps float64) *ValidationReport {
+	r := &ValidationReport{Eps: eps}
+	if d.wellFormedEps(r); r.Count[CondSquare] == 0 {
+		d.triangleEps(r)
+	}
+	return r
+}
+
+// wellFormedEps checks the conditions of ValidateEps other than the
+// triangle inequality.
+func (d DistanceMatrix) wellFormedEps(r *ValidationReport) {
+	eps := r.Eps
+	for i, di := range d {
+		if len(di) != len(d) {
+			r.add(CondSquare, math.Abs(float64(len(di)-len(d))), i)
+		}
+	}
+	if !r.OK() {
+		return
+	}
+	for i, di := range d {
+		for j, dij := range di {
+			switch {
+			case math.IsNaN(dij):
+				r.add(CondNotNaN, math.NaN(), i, j)
+			case dij < -eps:
+				r.add(CondNonNegative, -dij, i, j)
+			}
+		}
+	}
+	d.symmetricEps(r)
+	d.zeroDiagonalEps(r)
+}
+
+// SymmetricEps tests that off-diagonal elements are symmetric within
+// tolerance eps, collecting all violations.
+//
+// Elements that are NaN are not reported.
+func (d DistanceMatrix) SymmetricEps(eps float64) *ValidationReport {
+	r := &ValidationReport{Eps: eps}
+	d.symmetricEps(r)
+	return r
+}
+
+func (d DistanceMatrix) symmetricEps(r *ValidationReport) {
+	for i, di := range d {
+		for j, dij := range di[:i] {
+			if x := math.Abs(dij - d[j][i]); x > r.Eps {
+				r.add(CondSymmetric, x, i, j)
+			}
+		}
+	}
+}
+
+// ZeroDiagonalEps tests that diagonal elements are zero within tolerance
+// eps, collecting all violations.
+//
+// Elements that are NaN are not reported.
+func (d DistanceMatrix) ZeroDiagonalEps(eps float64) *ValidationReport {
+	r := &ValidationReport{Eps: eps}
+	d.zeroDiagonalEps(r)
+	return r
+}
+
+func (d DistanceMatrix) zeroDiagonalEps(r *ValidationReport) {
+	for i, di := range d {
+		if x := math.Abs(di[i]); x > r.Eps {
+			r.add(CondZeroDiagonal, x, i)
+		}
+	}
+}
+
+// TriangleInequalityEps tests the triangle inequality with tolerance eps,
+// collecting all violations.
+//
+// A violation is reported for i, j, k where d[i][j] + d[j][k] < d[i][k] - eps,
+// for each i > k and each j.  The matrix is assumed
+// symmetric.  As with TriangleInequality, the test is NaN weak.  Time
+// complexity is O(n^3).
+func (d DistanceMatrix) TriangleInequalityEps(eps float64) *ValidationReport {
+	r := &ValidationReport{Eps: eps}
+	d.triangleEps(r)
+	return r
+}
+
+func (d DistanceMatrix) triangleEps(r *ValidationReport) {
+	for i, di := range d {
+		for k, dk := range d[:i] {
+			dik := di[k]
+			for j, dij := range di {
+				// (compared as in TriangleInequality, exact for eps 0)
+				if s := dij + dk[j]; s < dik-r.Eps {
+					r.add(CondTriangle, dik-s, i, j, k)
+				}
+			}
+		}
+	}
+}
+
+// AdditiveEps tests the four-point condition with tolerance eps, collecting
+// all violations.
+//
+// For each set of four distinct indexes i > j > k > l, the two largest of
+// the sums d[i][j] + d[k][l], d[i][k] + d[j][l], and d[i][l] + d[j][k] must
+// differ by no more than eps.  Time complexity is O(n^4).
+func (d DistanceMatrix) AdditiveEps(eps float64) *ValidationReport {
+	r := &ValidationReport{Eps: eps}
+	for i, di := range d {
+		for j, dj := range d[:i] {
+			dij := di[j]
+			for k, dk := range d[:j] {
+				dik := di[k]
+				djk := dj[k]
+				for l := 0; l < k; l++ {
+					s1 := dij + dk[l]
+					s2 := dik + dj[l]
+					s3 := di[l] + djk
+					// order so s1 >= s2 are the two largest
+					if s1 < s2 {
+						s1, s2 = s2, s1
+					}
+					if s2 < s3 {
+						s2 = s3
+						if s1 < s2 {
+							s1, s2 = s2, s1
+						}
+					}
+					if x := s1 - s2; x > eps {
+						r.add(CondAdditive, x, i, j, k, l)
+					}
+				}
+			}
+		}
+	}
+	return r
+}
+
+// ThreePointEps tests the three-point condition with tolerance eps,
+// collecting all violations.
+//
+// For each set of three distinct indexes i > j > k, the two largest of
+// d[i][j], d[i][k], and d[j][k] must differ by no more than eps.  See
+// ThreePoint.  MaxSize[CondUltrametric] of the result measures how far d is
+// from ultrametric.  As with ThreePoint, the test is NaN weak.  Time
+// complexity is O(n^3).
+func (d DistanceMatrix) ThreePointEps(eps float64) *ValidationReport {
+	r := &ValidationReport{Eps: eps}
+	for i, di := range d {
+		for j, dj := range d[:i] {
+			for k := 0; k < j; k++ {
+				if x := threePointDev(di[j], di[k], dj[k]); x > eps {
+					r.add(CondUltrametric, x, i, j, k)
+				}
+			}
+		}
+	}
+	return r
+}
+
+// threePointDev returns the largest of a, b, c minus the second largest.
+func threePointDev(a, b, c float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	if b < c {
+		b = c
+		if a < b {
+			a, b = b, a
+		}
+	}
+	return a - b
+}
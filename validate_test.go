@@ -0,0 +1,140 @@
+// Public domain.
+
+package cluster_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soniakeys/cluster"
+)
+
+func ExampleDistanceMatrix_ValidateEps() {
+	d := cluster.DistanceMatrix{
+		{0, 4, 6, 1},
+		{4 + 1e-12, 0, 3, 2},
+		{6, 3, -1e-13, 5},
+		{1, 2, 5, 0},
+	}
+	fmt.Println(d.Validate())
+	r := d.ValidateEps(1e-9)
+	fmt.Println(r)
+	for _, v := range r.Violations {
+		fmt.Println(v.Indexes, v.Size)
+	}
+	// Output:
+	// negative element: d[2][2] (and 2 more violations)
+	// triangle inequality: 1, max 1.000000000001
+	// [1 3 0] 1.000000000001
+}
+
+func ExampleValidationError() {
+	d := cluster.DistanceMatrix{
+		{0, 1, math.NaN()},
+		{1, 0, -2},
+		{math.NaN(), -2, 0},
+	}
+	err := d.Validate()
+	var ve *cluster.ValidationError
+	if errors.As(err, &ve) {
+		fmt.Println(ve.Report)
+	}
+	var me *cluster.MatrixError
+	if errors.As(err, &me) {
+		fmt.Println(me)
+	}
+	// Output:
+	// NaN: 2, max NaN
+	// negative: 2, max 2
+	// NaN element: d[0][2]
+}
+
+func TestAdditiveEps(t *testing.T) {
+	a := cluster.DistanceMatrix{
+		{0, 13, 21, 22},
+		{13, 0, 12, 13},
+		{21, 12, 0, 13},
+		{22, 13, 13, 0},
+	}
+	if r := a.AdditiveEps(0); !r.OK() {
+		t.Fatal(r)
+	}
+	a[0][2] += 1e-10
+	a[2][0] = a[0][2]
+	if r := a.AdditiveEps(0); r.Total() != 1 {
+		t.Fatal(r)
+	}
+	if r := a.AdditiveEps(1e-9); !r.OK() {
+		t.Fatal(r)
+	}
+	na := cluster.DistanceMatrix{
+		{0, 3, 4, 3},
+		{3, 0, 4, 5},
+		{4, 4, 0, 2},
+		{3, 5, 2, 0},
+	}
+	r := na.AdditiveEps(0)
+	// sums 3+2, 4+5, 3+4: the two largest differ by 2
+	if r.Total() != 1 || r.Violations[0].Size != 2 ||
+		fmt.Sprint(r.Violations[0].Indexes) != "[3 2 1 0]" {
+		t.Fatal(r.Violations)
+	}
+}
+
+func ExampleDistanceMatrix_ThreePointEps() {
+	d, _, _ := cluster.RandomUltrametricMatrix(8, rand.New(rand.NewSource(1)))
+	ok, _, _, _ := d.ThreePoint()
+	fmt.Println(ok)
+	d[3][5] *= 1.01
+	d[5][3] = d[3][5]
+	r := d.ThreePointEps(1e-9)
+	fmt.Println(r.Count[cluster.CondUltrametric] > 0,
+		r.MaxSize[cluster.CondUltrametric] <= .01*d[3][5])
+	fmt.Println(d.ThreePointEps(.01 * d[3][5]).OK())
+	// Output:
+	// true
+	// true true
+	// true
+}
+
+func TestValidationReportLimit(t *testing.T) {
+	n := 40
+	d := make(cluster.DistanceMatrix, n)
+	for i := range d {
+		d[i] = make([]float64, n)
+		for j := range d[i] {
+			if i != j {
+				d[i][j] = float64(i + 2*j)
+			}
+		}
+	}
+	r := d.SymmetricEps(0)
+	if r.Total() != n*(n-1)/2 || len(r.Violations) != r.Total() {
+		t.Fatal(r)
+	}
+	r = d.TriangleInequalityEps(0)
+	if r.Total() <= cluster.MaxViolations ||
+		len(r.Violations) != cluster.MaxViolations {
+		t.Fatal(r.Total(), len(r.Violations))
+	}
+	if r := d.ZeroDiagonalEps(0); !r.OK() {
+		t.Fatal(r)
+	}
+}
+
+func TestThreePointNaN(t *testing.T) {
+	d := cluster.DistanceMatrix{
+		{0, 2, math.NaN()},
+		{2, 0, 4},
+		{math.NaN(), 4, 0},
+	}
+	if ok, i, j, k := d.ThreePoint(); !ok {
+		t.Fatal("ThreePoint", i, j, k)
+	}
+	if r := d.ThreePointEps(0); !r.OK() {
+		t.Fatal("ThreePointEps", r)
+	}
+}